@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var applyFile string
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Привести машину к состоянию, описанному в манифесте",
+	Long: `Читает манифест команды (по умолчанию devorchestrator.yaml), показывает план
+установки, обновления и удаления инструментов и выполняет его после подтверждения.`,
+	Example: "  dev-installer apply -f devorchestrator.yaml --yes",
+	RunE:    applyRun,
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", defaultManifestFile, "путь к манифесту")
+	applyCmd.Flags().BoolVar(&forceUninstall, "force", false, "удалять из раздела remove и инструменты, установленные не через DevOrchestrator")
+	applyCmd.Flags().BoolVar(&atomicInstall, "atomic", false, "при ошибке установки отменить все установленное этим запуском")
+}
+
+func applyRun(cmd *cobra.Command, args []string) error {
+	if err := checkAtomic(); err != nil {
+		return err
+	}
+	osType := detectOS()
+	fmt.Printf("Обнаруженная ОС: %s\n", osType)
+
+	manifest, err := loadManifest(applyFile)
+	if err != nil {
+		return err
+	}
+	if len(viaBackends) == 0 {
+		// Флаг --via важнее манифеста
+		viaBackends = manifest.Via
+	}
+
+	plan := buildPlan(cmd.Context(), manifest, osType)
+	plan.Print()
+	if plan.Empty() {
+		fmt.Println("Машина уже соответствует манифесту.")
+		return nil
+	}
+
+	if !assumeYes && !dryRun {
+		if err := confirmPlan(); err != nil {
+			return err
+		}
+	}
+
+	return applyPlan(cmd.Context(), plan, osType)
+}
+
+// confirmPlan запрашивает подтверждение перед выполнением плана
+func confirmPlan() error {
+	prompt := promptui.Prompt{
+		Label:     "Выполнить план",
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return errors.New("применение манифеста отменено")
+	}
+	return nil
+}
+
+// applyPlan выполняет план: сначала установку, затем обновление и удаление
+func applyPlan(ctx context.Context, plan *Plan, osType string) error {
+	if len(plan.Install)+len(plan.IDE) > 0 {
+		if err := performAction(ctx, ActionInstall, plan.Stack, stepTools(plan.IDE), stepTools(plan.Install), osType); err != nil {
+			return err
+		}
+	}
+	if len(plan.Update) > 0 {
+		if err := performAction(ctx, ActionUpdate, plan.Stack, nil, stepTools(plan.Update), osType); err != nil {
+			return err
+		}
+	}
+	if len(plan.Remove) > 0 {
+		if err := performAction(ctx, ActionUninstall, plan.Stack, nil, stepTools(plan.Remove), osType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
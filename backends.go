@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// systemBackend — имя, которым в --via и манифесте обозначается системный пакетный менеджер
+const systemBackend = "system"
+
+// viaBackends — предпочитаемые менеджеры версий в порядке приоритета (флаг --via или манифест).
+// Инструмент устанавливается через первый из них, который он поддерживает.
+var viaBackends []string
+
+// ToolBackend описывает инструмент для менеджера версий
+type ToolBackend struct {
+	// Package — кандидат менеджера (java, maven, node) или префикс версии по умолчанию (3 для pyenv)
+	Package string
+	// Version — шаблон идентификатора версии в менеджере, например для SDKMAN "21" → "21.0.4-tem"
+	Version string
+	// After — команды, выполняемые после установки (corepack enable)
+	After []string
+	// Remove — команды удаления вместо удаления пакета менеджера, если пакет общий
+	// с другим инструментом (corepack disable для Yarn вместо удаления Node.js)
+	Remove []string
+}
+
+// versionManager — пользовательский менеджер версий языка (SDKMAN, fnm, nvm, pyenv, goenv),
+// который устанавливает инструменты в домашний каталог без прав root.
+// Все команды — шаблоны с данными backendTemplateData, выполняемые в bash после init.
+type versionManager struct {
+	name string
+	// os — операционные системы, на которых работает менеджер
+	os []string
+	// check — команда, которая завершается успешно, если менеджер уже установлен
+	check string
+	// bootstrap — команды установки самого менеджера
+	bootstrap []string
+	// init — подготовка окружения оболочки, чтобы команды менеджера были доступны
+	init string
+
+	// installed — команда, которая завершается успешно, если инструмент установлен через менеджер
+	installed string
+	// install ставит версию, use делает ее версией по умолчанию
+	install string
+	use     string
+	upgrade string
+	remove  string
+	// current печатает идентификатор версии по умолчанию после установки (21.0.4-tem, v20.18.0);
+	// он запоминается, чтобы удалить именно установленную версию
+	current string
+	// hint — подсказка, которая печатается после установки
+	hint string
+	// paths — фрагменты путей, по которым видно, что исполняемый файл поставлен менеджером
+	paths []string
+}
+
+// backendTemplateData — данные для шаблонов команд менеджера версий
+type backendTemplateData struct {
+	Package string
+	// Version — выбранная версия или пустая строка, если подходит версия по умолчанию
+	Version string
+	OS      string
+	Arch    string
+}
+
+// versionManagers содержит поддерживаемые менеджеры версий
+var versionManagers = []*versionManager{
+	{
+		name:  "sdkman",
+		os:    []string{"linux", "darwin"},
+		check: `test -s "$HOME/.sdkman/bin/sdkman-init.sh"`,
+		bootstrap: []string{
+			`curl -fsSL "https://get.sdkman.io?rcupdate=true" | bash`,
+		},
+		init:      `export sdkman_auto_answer=true && . "$HOME/.sdkman/bin/sdkman-init.sh"`,
+		installed: `test -e "$HOME/.sdkman/candidates/{{.Package}}/current"`,
+		install:   `{{if .Version}}v={{.Version}} && sdk install {{.Package}} "$v"{{else}}sdk install {{.Package}}{{end}}`,
+		use:       `{{if .Version}}sdk default {{.Package}} "$v"{{end}}`,
+		upgrade:   `sdk upgrade {{.Package}}`,
+		remove:    `{{if .Version}}sdk uninstall --force {{.Package}} {{.Version}}{{else}}rm -rf "$HOME/.sdkman/candidates/{{.Package}}"{{end}}`,
+		current:   `sdk current {{.Package}} | awk '{print $NF}'`,
+		paths:     []string{"/.sdkman/"},
+	},
+	{
+		name:  "fnm",
+		os:    []string{"linux", "darwin"},
+		check: `command -v fnm || test -x "$HOME/.local/share/fnm/fnm"`,
+		bootstrap: []string{
+			`curl -fsSL https://fnm.vercel.app/install | bash -s -- --skip-shell`,
+		},
+		init:      `export PATH="$HOME/.local/share/fnm:$PATH" && eval "$(fnm env)"`,
+		installed: `fnm list | grep -q default`,
+		install:   `fnm install {{or .Version "--lts"}}`,
+		use:       `fnm default {{or .Version "lts-latest"}}`,
+		upgrade:   `fnm install --lts && fnm default lts-latest`,
+		remove:    `fnm uninstall {{or .Version "default"}}`,
+		current:   `fnm current`,
+		paths:     []string{"/fnm/", "/fnm_multishells/"},
+	},
+	{
+		name:  "nvm",
+		os:    []string{"linux", "darwin"},
+		check: `test -s "$HOME/.nvm/nvm.sh"`,
+		bootstrap: []string{
+			`curl -fsSL https://raw.githubusercontent.com/nvm-sh/nvm/v0.40.1/install.sh | bash`,
+		},
+		init:      `export NVM_DIR="$HOME/.nvm" && . "$NVM_DIR/nvm.sh"`,
+		installed: `nvm version default | grep -q '^v'`,
+		install:   `nvm install {{or .Version "--lts"}}`,
+		use:       `nvm alias default {{or .Version "lts/*"}}`,
+		upgrade:   `nvm install --lts --reinstall-packages-from=default && nvm alias default 'lts/*'`,
+		remove:    `nvm deactivate && nvm uninstall {{or .Version "default"}}`,
+		current:   `nvm version default`,
+		paths:     []string{"/.nvm/"},
+	},
+	{
+		name:  "pyenv",
+		os:    []string{"linux", "darwin"},
+		check: `command -v pyenv || test -x "$HOME/.pyenv/bin/pyenv"`,
+		bootstrap: []string{
+			`curl -fsSL https://pyenv.run | bash`,
+		},
+		init:      `export PYENV_ROOT="$HOME/.pyenv" && export PATH="$PYENV_ROOT/bin:$PATH" && eval "$(pyenv init -)"`,
+		installed: `pyenv version-name | grep -qv '^system$'`,
+		install:   `pyenv install -s {{or .Version .Package}}`,
+		use:       `pyenv global "$(pyenv latest {{or .Version .Package}})"`,
+		upgrade:   `pyenv install -s {{.Package}} && pyenv global "$(pyenv latest {{.Package}})"`,
+		remove:    `pyenv uninstall -f "$(pyenv latest {{or .Version .Package}})"`,
+		current:   `pyenv version-name`,
+		paths:     []string{"/.pyenv/"},
+	},
+	{
+		name:  "goenv",
+		os:    []string{"linux", "darwin"},
+		check: `command -v goenv || test -x "$HOME/.goenv/bin/goenv"`,
+		bootstrap: []string{
+			`git clone --depth 1 https://github.com/go-nv/goenv.git "$HOME/.goenv"`,
+		},
+		init:      `export GOENV_ROOT="$HOME/.goenv" && export PATH="$GOENV_ROOT/bin:$PATH" && eval "$(goenv init -)"`,
+		installed: `goenv version-name | grep -qv '^system$'`,
+		install:   `goenv install -s {{or .Version "latest"}}`,
+		use:       `goenv global {{if .Version}}{{.Version}}{{else}}"$(goenv versions --bare | sort -V | tail -1)"{{end}}`,
+		upgrade:   `goenv install -s latest && goenv global "$(goenv versions --bare | sort -V | tail -1)"`,
+		remove:    `goenv uninstall -f {{if .Version}}{{.Version}}{{else}}"$(goenv version-name)"{{end}}`,
+		current:   `goenv version-name`,
+		paths:     []string{"/.goenv/"},
+	},
+	{
+		// Официальный архив с go.dev: версии лежат в ~/.local/go/versions, активная — по ссылке ~/.local/go/current
+		name:      "go-tarball",
+		os:        []string{"linux", "darwin"},
+		check:     `command -v curl`,
+		installed: `test -x "$HOME/.local/go/current/bin/go"`,
+		install: `v={{if .Version}}go{{.Version}}{{else}}"$(curl -fsSL 'https://go.dev/VERSION?m=text' | head -1)"{{end}}` +
+			` && d="$(mktemp -d)" && curl -fsSL "https://go.dev/dl/$v.{{.OS}}-{{.Arch}}.tar.gz" | tar -xz -C "$d"` +
+			` && mkdir -p "$HOME/.local/go/versions" && rm -rf "$HOME/.local/go/versions/$v"` +
+			` && mv "$d/go" "$HOME/.local/go/versions/$v" && rm -rf "$d"`,
+		use:     `ln -sfn "$HOME/.local/go/versions/$v" "$HOME/.local/go/current"`,
+		upgrade: `v="$(curl -fsSL 'https://go.dev/VERSION?m=text' | head -1)" && test -d "$HOME/.local/go/versions/$v" || { d="$(mktemp -d)" && curl -fsSL "https://go.dev/dl/$v.{{.OS}}-{{.Arch}}.tar.gz" | tar -xz -C "$d" && mkdir -p "$HOME/.local/go/versions" && mv "$d/go" "$HOME/.local/go/versions/$v" && rm -rf "$d"; } && ln -sfn "$HOME/.local/go/versions/$v" "$HOME/.local/go/current"`,
+		remove:  `{{if .Version}}rm -rf "$HOME/.local/go/versions/go{{.Version}}"{{else}}rm -rf "$HOME/.local/go"{{end}}`,
+		current: `basename "$(readlink "$HOME/.local/go/current")" | sed 's/^go//'`,
+		hint:    `добавьте $HOME/.local/go/current/bin в PATH`,
+		paths:   []string{"/.local/go/"},
+	},
+}
+
+// versionManagerNames возвращает имена всех менеджеров версий
+func versionManagerNames() []string {
+	names := make([]string, 0, len(versionManagers))
+	for _, m := range versionManagers {
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// versionManagerByName ищет менеджер версий по имени
+func versionManagerByName(name string) (*versionManager, bool) {
+	for _, m := range versionManagers {
+		if m.name == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// validateBackends проверяет имена, переданные в --via или в манифесте
+func validateBackends(names []string) error {
+	for _, name := range names {
+		if name == systemBackend {
+			continue
+		}
+		if _, ok := versionManagerByName(name); !ok {
+			return fmt.Errorf("неизвестный менеджер версий %q, доступные: %s, %s", name, strings.Join(versionManagerNames(), ", "), systemBackend)
+		}
+	}
+	return nil
+}
+
+// chooseBackend выбирает менеджер версий для инструмента: первый подходящий из viaBackends,
+// иначе менеджер по умолчанию из каталога. Пустая строка означает системный пакетный менеджер.
+func chooseBackend(tool Tool) string {
+	for _, name := range viaBackends {
+		if name == systemBackend {
+			return ""
+		}
+		if _, ok := tool.Backends[name]; ok {
+			return name
+		}
+	}
+	return tool.Via
+}
+
+// bashScript формирует команду запуска скрипта в bash: менеджеры версий требуют bash
+func bashScript(script string) string {
+	return "bash -c '" + strings.ReplaceAll(script, "'", `'\''`) + "'"
+}
+
+// templateData готовит данные для шаблонов: выбирает версию по t.Pin и переводит ее
+// в идентификатор менеджера по шаблону version из каталога
+func (m *versionManager) templateData(t Tool) (backendTemplateData, error) {
+	backend := t.Backends[m.name]
+	platform := currentPlatform()
+	data := backendTemplateData{Package: backend.Package, OS: platform.OS, Arch: platform.Arch}
+	if t.InstalledRelease != "" {
+		// Идентификатор из записи об установке уже в терминах менеджера
+		data.Version = t.InstalledRelease
+		return data, nil
+	}
+	chosen, err := chosenVersion(t)
+	if err != nil || chosen == "" {
+		return data, err
+	}
+	data.Version = chosen
+	if backend.Version != "" {
+		v, err := parseVersion(chosen)
+		if err != nil {
+			return data, err
+		}
+		data.Version, err = renderTemplate(backend.Version, versionTemplateData{Version: chosen, Major: v.Major, Minor: v.Minor, Patch: v.Patch})
+		if err != nil {
+			return data, err
+		}
+	}
+	return data, nil
+}
+
+// chosenVersion выбирает версию по t.Pin; пусто, если версия не запрошена.
+// Без списка версий в каталоге ограничение передается менеджеру как есть: nvm и pyenv понимают "20" и "3.12"
+func chosenVersion(t Tool) (string, error) {
+	if t.Pin == "" || len(t.Versions) == 0 {
+		return t.Pin, nil
+	}
+	return pickVersion(t.Pin, t.Versions)
+}
+
+// script собирает команду действия: init менеджера и команды в одном вызове bash
+func (m *versionManager) script(steps ...string) string {
+	return bashScript(chain(append([]string{m.init}, steps...)...))
+}
+
+// chain соединяет команды через &&, пропуская пустые: у go-tarball нет init,
+// а пустая команда между && — синтаксическая ошибка bash
+func chain(steps ...string) string {
+	var parts []string
+	for _, step := range steps {
+		if step != "" {
+			parts = append(parts, step)
+		}
+	}
+	return strings.Join(parts, " && ")
+}
+
+// supports сообщает, работает ли менеджер в указанной ОС
+func (m *versionManager) supports(osType string) bool {
+	return contains(m.os, osType)
+}
+
+// isInstalled проверяет, установлен ли инструмент через менеджер
+func (m *versionManager) isInstalled(ctx context.Context, t Tool, osType string) bool {
+	if !m.supports(osType) {
+		return false
+	}
+	data, err := m.templateData(t)
+	if err != nil {
+		return false
+	}
+	check, err := renderTemplate(m.installed, data)
+	if err != nil {
+		return false
+	}
+	_, err = executor.Output(ctx, "bash", "-c", chain(m.check, m.init, check))
+	return err == nil
+}
+
+// noteCurrent запоминает идентификатор установленной версии. Шаблон version в каталоге вычисляется
+// при каждом запуске (последняя сборка Temurin), поэтому при удалении его нельзя вычислить заново.
+func (m *versionManager) noteCurrent(ctx context.Context, data backendTemplateData) {
+	if m.current == "" {
+		return
+	}
+	current, err := renderTemplate(m.current, data)
+	if err != nil {
+		return
+	}
+	output, err := executor.Output(ctx, "bash", "-c", chain(m.init, current))
+	if release := strings.TrimSpace(output); err == nil && release != "" {
+		noteRelease(ctx, release)
+	}
+}
+
+// sharedPackageOwner возвращает зависимость инструмента, которая ставится тем же пакетом менеджера:
+// npm через fnm — это пакет node, и его удаление удалило бы весь Node.js
+func (m *versionManager) sharedPackageOwner(t Tool) (string, bool) {
+	pkg := t.Backends[m.name].Package
+	for _, dep := range t.Requires {
+		if backend, ok := availableTools[dep].Backends[m.name]; ok && backend.Package == pkg {
+			return dep, true
+		}
+	}
+	return "", false
+}
+
+// ensure устанавливает сам менеджер, если его еще нет
+func (m *versionManager) ensure(ctx context.Context, osType string) error {
+	if _, err := executor.Output(ctx, "bash", "-c", m.check); err == nil {
+		return nil
+	}
+	fmt.Printf("Установка менеджера версий %s...\n", m.name)
+	for _, step := range m.bootstrap {
+		if err := runCommand(ctx, bashScript(step), osType); err != nil {
+			return fmt.Errorf("ошибка установки менеджера версий %s: %v", m.name, err)
+		}
+	}
+	return nil
+}
+
+// run выполняет действие над инструментом через менеджер версий
+func (m *versionManager) run(ctx context.Context, osType, command string, t Tool) error {
+	if !m.supports(osType) {
+		return fmt.Errorf("менеджер версий %s не поддерживается в %s", m.name, osType)
+	}
+	data, err := m.templateData(t)
+	if err != nil {
+		return err
+	}
+
+	// Менеджер версий один на пользователя: его установка и параллельные вызовы мешают друг другу
+	defer lockPackages(m.name)()
+
+	var templates []string
+	switch command {
+	case "install":
+		if err := m.ensure(ctx, osType); err != nil {
+			return err
+		}
+		templates = append([]string{m.install, m.use}, t.Backends[m.name].After...)
+	case "update":
+		templates = []string{m.upgrade}
+	case "uninstall":
+		if remove := t.Backends[m.name].Remove; len(remove) > 0 {
+			templates = remove
+			break
+		}
+		if owner, shared := m.sharedPackageOwner(t); shared {
+			return skipped("входит в %s (%s); чтобы удалить, удалите %s", owner, m.name, owner)
+		}
+		templates = []string{m.remove}
+	default:
+		return fmt.Errorf("неподдерживаемая команда %s для менеджера версий %s", command, m.name)
+	}
+
+	steps := make([]string, 0, len(templates))
+	for _, text := range templates {
+		step, err := renderTemplate(text, data)
+		if err != nil {
+			return err
+		}
+		steps = append(steps, step)
+	}
+
+	log.Printf("Менеджер версий %s, пакет %s, версия %q\n", m.name, data.Package, data.Version)
+	if command == "install" {
+		// Запоминается выбранная версия, а не ограничение: удалить нужно именно ее
+		chosen, _ := chosenVersion(t)
+		notePackage(ctx, data.Package, chosen)
+	}
+	if err := runCommand(ctx, m.script(steps...), osType); err != nil {
+		return err
+	}
+	if command == "install" && !dryRun {
+		m.noteCurrent(ctx, data)
+	}
+	if command == "install" && m.hint != "" && !dryRun {
+		fmt.Printf("%s: %s\n", t.Description, m.hint)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var nodeTool = Tool{
+	DetectBinary: "node",
+	Description:  "Node.js",
+	Packages:     map[string]string{"linux": "nodejs"},
+	Versions:     []string{"18.20.4", "20.18.0"},
+	Backends: map[string]ToolBackend{
+		"fnm": {Package: "node"},
+		"nvm": {Package: "node", After: []string{"corepack enable yarn"}},
+	},
+}
+
+func TestChooseBackend(t *testing.T) {
+	defer func(saved []string) { viaBackends = saved }(viaBackends)
+
+	tool := nodeTool
+	tool.Via = "nvm"
+	tests := []struct {
+		via  []string
+		want string
+	}{
+		{nil, "nvm"},
+		{[]string{"sdkman", "fnm"}, "fnm"},
+		{[]string{"sdkman"}, "nvm"},
+		{[]string{"system", "fnm"}, ""},
+	}
+	for _, tt := range tests {
+		viaBackends = tt.via
+		if got := chooseBackend(tool); got != tt.want {
+			t.Errorf("--via %v: выбран %q, ожидался %q", tt.via, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteCommandViaBackend(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t)
+
+	tool := nodeTool
+	tool.Via = "nvm"
+	tool.Pin = "20"
+	if err := executeCommand(context.Background(), "linux", "install", tool); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`bash -c 'curl -fsSL https://raw.githubusercontent.com/nvm-sh/nvm/v0.40.1/install.sh | bash'`,
+		`bash -c 'export NVM_DIR="$HOME/.nvm" && . "$NVM_DIR/nvm.sh" && nvm install 20.18.0 && nvm alias default 20.18.0 && corepack enable yarn'`,
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("команды:\n%q\nожидалось:\n%q", got, want)
+	}
+}
+
+func TestExecuteCommandViaInstalledBackend(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t)
+	fake.outputs[`bash -c command -v fnm || test -x "$HOME/.local/share/fnm/fnm"`] = "/usr/bin/fnm\n"
+
+	tool := nodeTool
+	tool.Via = "fnm"
+	for command, want := range map[string]string{
+		"install":   `bash -c 'export PATH="$HOME/.local/share/fnm:$PATH" && eval "$(fnm env)" && fnm install --lts && fnm default lts-latest'`,
+		"uninstall": `bash -c 'export PATH="$HOME/.local/share/fnm:$PATH" && eval "$(fnm env)" && fnm uninstall default'`,
+	} {
+		fake.runs = nil
+		if err := executeCommand(context.Background(), "linux", command, tool); err != nil {
+			t.Fatal(err)
+		}
+		if got := fake.commands(); !reflect.DeepEqual(got, []string{want}) {
+			t.Errorf("%s: команды %q, ожидалось %q", command, got, want)
+		}
+	}
+}
+
+func TestExecuteCommandViaUnsupportedOS(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "windows", Arch: "amd64"})
+	useFakeExecutor(t, "choco")
+
+	tool := nodeTool
+	tool.Via = "fnm"
+	err := executeCommand(context.Background(), "windows", "install", tool)
+	if err == nil || !strings.Contains(err.Error(), "не поддерживается в windows") {
+		t.Fatalf("ожидалась ошибка неподдерживаемой ОС, получено %v", err)
+	}
+}
+
+func TestBashScriptQuoting(t *testing.T) {
+	got := bashScript(`echo 'a b'`)
+	if want := `bash -c 'echo '\''a b'\'''`; got != want {
+		t.Errorf("получено %s, ожидалось %s", got, want)
+	}
+}
+
+func TestUninstallSharedRuntimeKeepsNode(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t)
+	fake.outputs[`bash -c command -v fnm || test -x "$HOME/.local/share/fnm/fnm"`] = "/usr/bin/fnm\n"
+	npm := Tool{DetectBinary: "npm", Description: "npm", Via: "fnm", Requires: []string{"Node.js"}, Backends: map[string]ToolBackend{"fnm": {Package: "node"}}}
+	yarn := Tool{DetectBinary: "yarn", Description: "Yarn", Via: "fnm", Requires: []string{"Node.js"}, Backends: map[string]ToolBackend{
+		"fnm": {Package: "node", After: []string{"corepack enable yarn"}, Remove: []string{"corepack disable yarn"}},
+	}}
+	useTools(t, map[string]Tool{"Node.js": nodeTool, "npm": npm, "Yarn": yarn})
+
+	// npm нельзя удалить отдельно от Node.js: команда пропускается
+	err := executeCommand(context.Background(), "linux", "uninstall", npm)
+	if result := newToolResult("npm", err); result.Outcome != OutcomeSkipped || !strings.Contains(result.Detail, "Node.js") {
+		t.Errorf("удаление npm: %+v", result)
+	}
+	if got := fake.commands(); len(got) != 0 {
+		t.Errorf("при удалении npm выполнены команды %q", got)
+	}
+
+	// Yarn выключается через corepack, Node.js остается
+	if err := executeCommand(context.Background(), "linux", "uninstall", yarn); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`bash -c 'export PATH="$HOME/.local/share/fnm:$PATH" && eval "$(fnm env)" && corepack disable yarn'`}
+	if got := fake.commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("удаление Yarn: команды %q, ожидалось %q", got, want)
+	}
+}
+
+func TestGoTarballPresenceCheckRunsInBash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash не найден")
+	}
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	prev := executor
+	executor = systemExecutor{}
+	t.Cleanup(func() { executor = prev })
+
+	// У go-tarball нет init: проверка не должна превращаться в "command -v curl &&  && test -x ..."
+	home, bin := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "curl"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+filepath.Dir(bash))
+
+	m, _ := versionManagerByName("go-tarball")
+	tool := Tool{Backends: map[string]ToolBackend{"go-tarball": {Package: "go"}}}
+	if m.isInstalled(context.Background(), tool, "linux") {
+		t.Fatal("Go найден до установки")
+	}
+	goBin := filepath.Join(home, ".local", "go", "current", "bin")
+	if err := os.MkdirAll(goBin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(goBin, "go"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if !m.isInstalled(context.Background(), tool, "linux") {
+		t.Error("установленный Go не найден")
+	}
+}
+
+func TestUninstallRemovesRecordedRelease(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t)
+	const initScript = `export sdkman_auto_answer=true && . "$HOME/.sdkman/bin/sdkman-init.sh"`
+	fake.outputs[`bash -c test -s "$HOME/.sdkman/bin/sdkman-init.sh"`] = ""
+	fake.outputs["bash -c "+initScript+` && sdk current java | awk '{print $NF}'`] = "21.0.4-tem\n"
+	java := javaTool
+	java.Via = "sdkman"
+	java.Backends = map[string]ToolBackend{"sdkman": {Package: "java", Version: `$(sdk list java | grep -Eo "{{.Major}}(\.[0-9]+)*-tem" | sort -V | tail -1)`}}
+	useTools(t, map[string]Tool{"OpenJDK": java})
+	viaBackends = []string{"sdkman"}
+	t.Cleanup(func() { viaBackends = nil })
+
+	if err := installStack(context.Background(), EssentialStack, nil, []string{"OpenJDK@21"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	state, _ := loadState()
+	if record := state.Tools["OpenJDK"]; record.Backend != "sdkman" || record.Release != "21.0.4-tem" {
+		t.Fatalf("запись OpenJDK: %+v", record)
+	}
+
+	// Удаляется записанная сборка, а не последняя сборка на момент удаления и не весь каталог java
+	fake.outputs["bash -c "+`test -s "$HOME/.sdkman/bin/sdkman-init.sh" && `+initScript+` && test -e "$HOME/.sdkman/candidates/java/current"`] = ""
+	fake.runs = nil
+	if err := performUninstall(context.Background(), []string{"OpenJDK"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{bashScript(initScript + " && sdk uninstall --force java 21.0.4-tem")}
+	if got := fake.commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("команды %q, ожидалось %q", got, want)
+	}
+}
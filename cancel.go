@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// totalTimeout ограничивает время всей операции, stepTimeout — одной команды; 0 — без ограничения
+var (
+	totalTimeout time.Duration
+	stepTimeout  time.Duration
+)
+
+// releaseTimeout освобождает таймер --timeout
+var releaseTimeout context.CancelFunc = func() {}
+
+// receivedSignal — сигнал, по которому прервана работа
+var receivedSignal atomic.Value
+
+// interruptSignal возвращает сигнал для дочерних процессов: полученный программой или SIGTERM
+func interruptSignal() syscall.Signal {
+	if sig, ok := receivedSignal.Load().(syscall.Signal); ok {
+		return sig
+	}
+	return syscall.SIGTERM
+}
+
+// withSignals возвращает контекст, который отменяется по SIGINT или SIGTERM.
+// Повторный сигнал завершает программу сразу.
+func withSignals(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			if s, ok := sig.(syscall.Signal); ok {
+				receivedSignal.Store(s)
+			}
+			fmt.Fprintf(os.Stderr, "\nПолучен сигнал %v, останавливаем запущенные команды...\n", sig)
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(signals)
+			return
+		}
+		<-signals
+		os.Exit(130)
+	}()
+	return ctx, cancel
+}
+
+// stepContext ограничивает время выполнения одной команды
+func stepContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if stepTimeout > 0 {
+		return context.WithTimeout(ctx, stepTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// commandError поясняет ошибку команды, прерванной по тайм-ауту или сигналу
+func commandError(ctx context.Context, command string, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("превышено время ожидания, команда остановлена: %s", command)
+	case errors.Is(ctx.Err(), context.Canceled):
+		if sig, ok := receivedSignal.Load().(syscall.Signal); ok {
+			return fmt.Errorf("прервано сигналом %v: %s", sig, command)
+		}
+		return fmt.Errorf("прервано: %s", command)
+	}
+	// %w сохраняет вывод команды для classifyFailure
+	return fmt.Errorf("ошибка выполнения команды: %w", err)
+}
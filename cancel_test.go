@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCommandStepTimeout(t *testing.T) {
+	fake := useFakeExecutor(t)
+	fake.onRun = func(string) { time.Sleep(50 * time.Millisecond) }
+	defer func(saved time.Duration) { stepTimeout = saved }(stepTimeout)
+	stepTimeout = 10 * time.Millisecond
+
+	err := runCommand(context.Background(), "sudo apt-get update", "linux")
+	if err == nil || !strings.Contains(err.Error(), "превышено время ожидания") || !strings.Contains(err.Error(), "apt-get update") {
+		t.Fatalf("ошибка %v", err)
+	}
+}
+
+func TestRunCommandCanceled(t *testing.T) {
+	fake := useFakeExecutor(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := runCommand(ctx, "curl -fsSL https://example.com | sh", "linux")
+	if err == nil || !strings.Contains(err.Error(), "прервано") {
+		t.Fatalf("ошибка %v", err)
+	}
+	if len(fake.runs) != 0 {
+		t.Errorf("после отмены выполнены команды %q", fake.commands())
+	}
+}
+
+func TestInstallStackReportsInterruptedTools(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	useDependentTools(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fake.onRun = func(string) { cancel() }
+
+	err := installStack(ctx, EssentialStack, nil, []string{"Node.js", "npm"}, "linux")
+	if err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	for _, want := range []string{"ошибка установки Node.js: прервано", "npm не обработан: операция прервана"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("в ошибке %q нет %q", err, want)
+		}
+	}
+}
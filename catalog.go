@@ -0,0 +1,494 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultCatalog — встроенный каталог инструментов
+//
+//go:embed catalog.yaml
+var defaultCatalog []byte
+
+// installHooks содержит встроенные функции установки, на которые ссылается поле hook каталога
+var installHooks = map[string]func(ctx context.Context) error{
+	"oh-my-zsh": installOhMyZsh,
+	"astronvim": installAstroNvim,
+}
+
+// hookPaths — файлы и каталоги, которые создают встроенные функции установки.
+// Если их не было до установки, uninstall --purge удаляет их.
+var hookPaths = map[string][]string{
+	"oh-my-zsh": {"~/.oh-my-zsh"},
+	"astronvim": {"~/.config/nvim"},
+}
+
+// knownPackageKeys возвращает допустимые ключи в packages и install: ОС и пакетные менеджеры
+func knownPackageKeys() []string {
+	return append([]string{"windows", "darwin", "linux"}, packageManagerNames()...)
+}
+
+// Catalog — содержимое файла каталога
+type Catalog struct {
+	Tools []CatalogTool `yaml:"tools"`
+}
+
+// CatalogTool — описание инструмента в каталоге
+type CatalogTool struct {
+	Name        string              `yaml:"name"`
+	Command     string              `yaml:"command"`
+	Description string              `yaml:"description"`
+	IDE         *bool               `yaml:"ide"`
+	Stacks      []string            `yaml:"stacks"`
+	Package     string              `yaml:"package"`
+	Packages    map[string]string   `yaml:"packages"`
+	Unsupported []string            `yaml:"unsupported"`
+	Install     map[string][]string `yaml:"install"`
+	Uninstall   map[string][]string `yaml:"uninstall"`
+	Detect      *CatalogDetect      `yaml:"detect"`
+	Hook        string              `yaml:"hook"`
+	Requires    []string            `yaml:"requires"`
+	Version     *CatalogVersion     `yaml:"version"`
+	Versioned   *CatalogVersioned   `yaml:"versioned"`
+	// Backends — установка через менеджеры версий, Via — менеджер по умолчанию
+	Backends map[string]CatalogBackend `yaml:"backends"`
+	Via      string                    `yaml:"via"`
+}
+
+// CatalogBackend описывает инструмент в менеджере версий.
+// В YAML его можно записать строкой (sdkman: maven) или объектом с полями package, version, after и remove.
+type CatalogBackend struct {
+	Package string   `yaml:"package"`
+	Version string   `yaml:"version"`
+	After   []string `yaml:"after"`
+	Remove  []string `yaml:"remove"`
+}
+
+// UnmarshalYAML поддерживает краткую запись кандидата строкой
+func (b *CatalogBackend) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		b.Package = value.Value
+		return nil
+	}
+	type plain CatalogBackend
+	return value.Decode((*plain)(b))
+}
+
+// CatalogDetect описывает, как найти приложение, которое не кладет исполняемый файл в PATH
+type CatalogDetect struct {
+	Snap     string `yaml:"snap"`
+	App      string `yaml:"app"`
+	Winget   string `yaml:"winget"`
+	Registry string `yaml:"registry"`
+}
+
+// CatalogVersioned описывает установку конкретной версии инструмента
+type CatalogVersioned struct {
+	// Versions — известные версии, из которых выбирается подходящая под ограничение
+	Versions []string `yaml:"versions"`
+	// Packages — шаблоны имен пакетов по менеджеру или ОС, например openjdk-{{.Major}}-jdk
+	Packages map[string]string `yaml:"packages"`
+}
+
+// CatalogVersion описывает, как узнать установленную версию инструмента
+type CatalogVersion struct {
+	// Command — команда оболочки, печатающая версию; для вывода в stderr добавьте 2>&1
+	Command string `yaml:"command"`
+	// Pattern — регулярное выражение с одной группой, выделяющей версию
+	Pattern string `yaml:"pattern"`
+}
+
+// catalogDir возвращает каталог пользовательских дополнений к каталогу
+func catalogDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "devorchestrator", "catalog.d")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "devorchestrator", "catalog.d")
+}
+
+// loadCatalog читает встроенный каталог и пользовательские дополнения,
+// проверяет их и заполняет availableTools и toolsByStack
+func loadCatalog() error {
+	catalog, err := parseCatalog(defaultCatalog, "встроенный каталог")
+	if err != nil {
+		return err
+	}
+
+	if dir := catalogDir(); dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+		if err != nil {
+			return fmt.Errorf("ошибка поиска файлов каталога: %v", err)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("ошибка чтения каталога %s: %v", file, err)
+			}
+			extra, err := parseCatalog(data, file)
+			if err != nil {
+				return err
+			}
+			catalog.merge(extra)
+		}
+	}
+
+	if err := catalog.validate(); err != nil {
+		return err
+	}
+	catalog.apply()
+	return nil
+}
+
+// parseCatalog разбирает файл каталога
+func parseCatalog(data []byte, source string) (*Catalog, error) {
+	var c Catalog
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("ошибка разбора каталога (%s): %v", source, err)
+	}
+	return &c, nil
+}
+
+// merge добавляет инструменты из extra; поля инструментов с тем же именем переопределяются
+func (c *Catalog) merge(extra *Catalog) {
+	for _, tool := range extra.Tools {
+		i := c.index(tool.Name)
+		if i < 0 {
+			c.Tools = append(c.Tools, tool)
+			continue
+		}
+
+		base := &c.Tools[i]
+		if tool.Command != "" {
+			base.Command = tool.Command
+		}
+		if tool.Package != "" {
+			base.Package = tool.Package
+		}
+		if tool.Description != "" {
+			base.Description = tool.Description
+		}
+		if tool.IDE != nil {
+			base.IDE = tool.IDE
+		}
+		if tool.Stacks != nil {
+			base.Stacks = tool.Stacks
+		}
+		if tool.Hook != "" {
+			base.Hook = tool.Hook
+		}
+		if tool.Version != nil {
+			base.Version = tool.Version
+		}
+		if tool.Versioned != nil {
+			base.Versioned = tool.Versioned
+		}
+		if tool.Via != "" {
+			base.Via = tool.Via
+		}
+		if tool.Requires != nil {
+			base.Requires = tool.Requires
+		}
+		if tool.Unsupported != nil {
+			base.Unsupported = tool.Unsupported
+		}
+		if tool.Detect != nil {
+			base.Detect = tool.Detect
+		}
+		for name, backend := range tool.Backends {
+			if base.Backends == nil {
+				base.Backends = make(map[string]CatalogBackend)
+			}
+			base.Backends[name] = backend
+		}
+		for key, pkg := range tool.Packages {
+			if base.Packages == nil {
+				base.Packages = make(map[string]string)
+			}
+			base.Packages[key] = pkg
+		}
+		for key, steps := range tool.Install {
+			if base.Install == nil {
+				base.Install = make(map[string][]string)
+			}
+			base.Install[key] = steps
+		}
+		for key, steps := range tool.Uninstall {
+			if base.Uninstall == nil {
+				base.Uninstall = make(map[string][]string)
+			}
+			base.Uninstall[key] = steps
+		}
+	}
+}
+
+// index возвращает позицию инструмента с указанным именем или -1
+func (c *Catalog) index(name string) int {
+	for i, tool := range c.Tools {
+		if tool.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// validate проверяет согласованность каталога
+func (c *Catalog) validate() error {
+	var problems []string
+	names := make(map[string]bool)
+	commands := make(map[string]string)
+
+	for _, tool := range c.Tools {
+		if tool.Name == "" {
+			problems = append(problems, fmt.Sprintf("инструмент с командой %q без имени", tool.Command))
+			continue
+		}
+		if names[tool.Name] {
+			problems = append(problems, fmt.Sprintf("%s: инструмент описан дважды", tool.Name))
+		}
+		names[tool.Name] = true
+
+		if tool.Command == "" {
+			problems = append(problems, fmt.Sprintf("%s: не указана команда (command)", tool.Name))
+		} else if other, dup := commands[tool.Command]; dup {
+			problems = append(problems, fmt.Sprintf("%s: команда %q уже используется инструментом %s", tool.Name, tool.Command, other))
+		} else {
+			commands[tool.Command] = tool.Name
+		}
+
+		if len(tool.Stacks) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: не указан ни один стек", tool.Name))
+		}
+		for _, stack := range tool.Stacks {
+			if _, err := parseStack(stack); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", tool.Name, err))
+			}
+		}
+		for key, pkg := range tool.Packages {
+			if !contains(knownPackageKeys(), key) {
+				problems = append(problems, fmt.Sprintf("%s: неизвестный ключ packages %q", tool.Name, key))
+			}
+			if err := checkTemplate(pkg, Platform{}); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", tool.Name, err))
+			}
+		}
+		if err := checkTemplate(tool.Package, Platform{}); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", tool.Name, err))
+		}
+		for _, name := range tool.Unsupported {
+			if packageManagerByName(name) == nil {
+				problems = append(problems, fmt.Sprintf("%s: неизвестный пакетный менеджер в unsupported %q", tool.Name, name))
+			}
+		}
+		for section, recipes := range map[string]map[string][]string{"install": tool.Install, "uninstall": tool.Uninstall} {
+			for key, steps := range recipes {
+				if !contains(knownPackageKeys(), key) {
+					problems = append(problems, fmt.Sprintf("%s: неизвестный ключ %s %q", tool.Name, section, key))
+				}
+				if len(steps) == 0 {
+					problems = append(problems, fmt.Sprintf("%s: пустой список команд %s для %s", tool.Name, section, key))
+				}
+				for _, step := range steps {
+					if err := checkTemplate(step, Platform{}); err != nil {
+						problems = append(problems, fmt.Sprintf("%s: %v", tool.Name, err))
+					}
+				}
+			}
+		}
+		if tool.Detect != nil && tool.Detect.App != "" && !strings.HasSuffix(tool.Detect.App, ".app") {
+			problems = append(problems, fmt.Sprintf("%s: detect.app должен оканчиваться на .app", tool.Name))
+		}
+		if tool.Hook != "" {
+			if _, ok := installHooks[tool.Hook]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: неизвестная функция установки %q", tool.Name, tool.Hook))
+			}
+		}
+		if tool.Version != nil {
+			if tool.Version.Command == "" {
+				problems = append(problems, fmt.Sprintf("%s: не указана команда получения версии", tool.Name))
+			}
+			if tool.Version.Pattern != "" {
+				if re, err := regexp.Compile(tool.Version.Pattern); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: неверный шаблон версии: %v", tool.Name, err))
+				} else if re.NumSubexp() != 1 {
+					problems = append(problems, fmt.Sprintf("%s: шаблон версии должен содержать одну группу", tool.Name))
+				}
+			}
+		}
+		if tool.Versioned != nil {
+			for _, version := range tool.Versioned.Versions {
+				if _, err := parseVersion(version); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %v", tool.Name, err))
+				}
+			}
+			for key, pkg := range tool.Versioned.Packages {
+				if !contains(knownPackageKeys(), key) {
+					problems = append(problems, fmt.Sprintf("%s: неизвестный ключ versioned.packages %q", tool.Name, key))
+				}
+				if err := checkTemplate(pkg, versionTemplateData{}); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %v", tool.Name, err))
+				}
+			}
+		}
+		for name, backend := range tool.Backends {
+			if _, ok := versionManagerByName(name); !ok {
+				problems = append(problems, fmt.Sprintf("%s: неизвестный менеджер версий %q", tool.Name, name))
+			}
+			if backend.Package == "" {
+				problems = append(problems, fmt.Sprintf("%s: не указан пакет для менеджера версий %s", tool.Name, name))
+			}
+			if err := checkTemplate(backend.Version, versionTemplateData{}); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", tool.Name, err))
+			}
+		}
+		if _, ok := tool.Backends[tool.Via]; tool.Via != "" && !ok {
+			problems = append(problems, fmt.Sprintf("%s: менеджер версий по умолчанию %q не описан в backends", tool.Name, tool.Via))
+		}
+	}
+
+	for _, tool := range c.Tools {
+		for _, dep := range tool.Requires {
+			if !names[dep] {
+				problems = append(problems, fmt.Sprintf("%s: неизвестная зависимость %q", tool.Name, dep))
+			}
+		}
+	}
+	if cycle := c.findCycle(); cycle != nil {
+		problems = append(problems, fmt.Sprintf("циклическая зависимость: %s", strings.Join(cycle, " → ")))
+	}
+
+	if len(problems) > 0 {
+		return errors.New("каталог инструментов содержит ошибки:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// findCycle ищет цикл в зависимостях requires и возвращает его или nil
+func (c *Catalog) findCycle() []string {
+	requires := make(map[string][]string, len(c.Tools))
+	for _, tool := range c.Tools {
+		requires[tool.Name] = tool.Requires
+	}
+
+	const (
+		unvisited = iota
+		inProgress
+		finished
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = inProgress
+		path = append(path, name)
+		for _, dep := range requires[name] {
+			switch state[dep] {
+			case inProgress:
+				for i, item := range path {
+					if item == dep {
+						return append(append([]string(nil), path[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = finished
+		return nil
+	}
+
+	for _, tool := range c.Tools {
+		if state[tool.Name] == unvisited {
+			if cycle := visit(tool.Name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// apply заполняет глобальные таблицы инструментов из каталога
+func (c *Catalog) apply() {
+	availableTools = make(map[string]Tool, len(c.Tools))
+	toolsByStack = make(map[string][]string)
+	ideByStack = make(map[string][]string)
+
+	for _, entry := range c.Tools {
+		tool := Tool{
+			DetectBinary: entry.Command,
+			PackageID:    entry.Package,
+			Description:  entry.Description,
+			Packages:     entry.Packages,
+			Steps:        entry.Install,
+			UndoSteps:    entry.Uninstall,
+			Unsupported:  entry.Unsupported,
+			Requires:     entry.Requires,
+		}
+		if tool.Description == "" {
+			tool.Description = entry.Name
+		}
+		if entry.Hook != "" {
+			tool.InstallFunc = installHooks[entry.Hook]
+			tool.HookPaths = hookPaths[entry.Hook]
+		}
+		if len(entry.Backends) > 0 {
+			tool.Backends = make(map[string]ToolBackend, len(entry.Backends))
+			for name, backend := range entry.Backends {
+				tool.Backends[name] = ToolBackend{Package: backend.Package, Version: backend.Version, After: backend.After, Remove: backend.Remove}
+			}
+			tool.Via = entry.Via
+		}
+		if entry.Detect != nil {
+			tool.Detect = ToolDetect{Snap: entry.Detect.Snap, App: entry.Detect.App, Winget: entry.Detect.Winget, Registry: entry.Detect.Registry}
+		}
+		if entry.Versioned != nil {
+			tool.Versions = entry.Versioned.Versions
+			tool.VersionedPackages = entry.Versioned.Packages
+		}
+		if entry.Version != nil {
+			tool.VersionCommand = entry.Version.Command
+			if entry.Version.Pattern != "" {
+				tool.VersionPattern = regexp.MustCompile(entry.Version.Pattern)
+			}
+		}
+		availableTools[entry.Name] = tool
+
+		for _, name := range entry.Stacks {
+			stack, _ := parseStack(name)
+			if entry.IDE != nil && *entry.IDE {
+				ideByStack[string(stack)] = append(ideByStack[string(stack)], entry.Name)
+			} else {
+				toolsByStack[string(stack)] = append(toolsByStack[string(stack)], entry.Name)
+			}
+		}
+	}
+}
+
+// contains сообщает, входит ли строка в список
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Работа с каталогом инструментов",
+}
+
+var catalogCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Проверить, что каждый инструмент можно установить каждым поддерживаемым пакетным менеджером",
+	Long: `Проверяет каталог вместе с пользовательскими дополнениями: для каждого пакетного менеджера
+у инструмента должен быть пакет, специальные команды установки или встроенная функция установки,
+либо менеджер должен быть явно указан в unsupported. У каждого набора специальных команд install
+должны быть парные команды uninstall. Также ищутся специальные команды и имена пакетов, которые
+никогда не будут использованы.`,
+	Args: cobra.NoArgs,
+	RunE: catalogCheckRun,
+}
+
+func init() {
+	catalogCmd.AddCommand(catalogCheckCmd)
+}
+
+func catalogCheckRun(cmd *cobra.Command, args []string) error {
+	problems := checkCatalogCoverage(availableTools)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Printf("  %s\n", problem)
+		}
+		return fmt.Errorf("каталог не прошел проверку: проблем %d", len(problems))
+	}
+	fmt.Printf("Каталог согласован: инструментов %d, пакетных менеджеров %d.\n", len(availableTools), len(packageManagers))
+	return nil
+}
+
+// sortedToolNames возвращает имена инструментов в алфавитном порядке
+func sortedToolNames(catalog map[string]Tool) []string {
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkCatalogCoverage ищет инструменты, которые нельзя установить одним из пакетных менеджеров,
+// и описания установки, до которых выбор не дойдет
+func checkCatalogCoverage(catalog map[string]Tool) []string {
+	var problems []string
+	for _, name := range sortedToolNames(catalog) {
+		tool := catalog[name]
+		for _, pm := range packageManagers {
+			pkg := tool.packageName(pm.OS(), pm.Name())
+			steps := tool.installSteps(pm.OS(), pm.Name())
+			switch {
+			case pkg == "" && len(steps) == 0 && tool.InstallFunc == nil && !contains(tool.Unsupported, pm.Name()):
+				problems = append(problems, fmt.Sprintf("%s: нет пакета или команд установки для %s", name, pm.Name()))
+			case pm.Name() == "emerge" && pkg != "" && !strings.Contains(pkg, "/"):
+				problems = append(problems, fmt.Sprintf("%s: пакет %q для emerge указан без категории", name, pkg))
+			}
+			if contains(tool.Unsupported, pm.Name()) && tool.Packages[pm.Name()] != "" {
+				problems = append(problems, fmt.Sprintf("%s: пакет для %s указан, но менеджер отмечен в unsupported", name, pm.Name()))
+			}
+		}
+
+		for _, key := range knownPackageKeys() {
+			switch install, undo := len(tool.Steps[key]) > 0, len(tool.UndoSteps[key]) > 0; {
+			case install && !undo:
+				problems = append(problems, fmt.Sprintf("%s: для команд install.%s не заданы команды uninstall", name, key))
+			case undo && !install:
+				problems = append(problems, fmt.Sprintf("%s: команды uninstall.%s не соответствуют командам install", name, key))
+			}
+		}
+		if tool.InstallFunc != nil && len(tool.Steps) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: команды install не используются, установку выполняет hook", name))
+		}
+		for _, osType := range []string{"windows", "darwin", "linux"} {
+			if len(tool.Steps[osType]) > 0 && overridden(osType, func(pm string) bool { return len(tool.Steps[pm]) > 0 }) {
+				problems = append(problems, fmt.Sprintf("%s: команды install.%s недостижимы, для каждого менеджера %s заданы свои", name, osType, osType))
+			}
+			if tool.Packages[osType] != "" && overridden(osType, func(pm string) bool {
+				return tool.Packages[pm] != "" || contains(tool.Unsupported, pm)
+			}) {
+				problems = append(problems, fmt.Sprintf("%s: пакет packages.%s не используется, для каждого менеджера %s задан свой", name, osType, osType))
+			}
+		}
+	}
+	return problems
+}
+
+// overridden сообщает, что для всех пакетных менеджеров ОС задано собственное значение
+func overridden(osType string, has func(pm string) bool) bool {
+	for _, pm := range packageManagers {
+		if pm.OS() == osType && !has(pm.Name()) {
+			return false
+		}
+	}
+	return true
+}
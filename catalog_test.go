@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinCatalogCoversEveryPackageManager(t *testing.T) {
+	catalog, err := parseCatalog(defaultCatalog, "встроенный каталог")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.validate(); err != nil {
+		t.Fatal(err)
+	}
+	prevTools, prevStacks, prevIDE := availableTools, toolsByStack, ideByStack
+	t.Cleanup(func() { availableTools, toolsByStack, ideByStack = prevTools, prevStacks, prevIDE })
+	catalog.apply()
+
+	if problems := checkCatalogCoverage(availableTools); len(problems) > 0 {
+		t.Errorf("проблемы каталога:\n  %s", strings.Join(problems, "\n  "))
+	}
+}
+
+func TestCatalogCoverageFindsGapsAndUnreachableRecipes(t *testing.T) {
+	all := func(value string) map[string]string {
+		packages := make(map[string]string)
+		for _, name := range packageManagerNames() {
+			packages[name] = value
+		}
+		return packages
+	}
+	tools := map[string]Tool{
+		"Maven": {DetectBinary: "mvn", Packages: map[string]string{"apt": "maven", "brew": "maven", "choco": "maven"}},
+		"IntelliJ IDEA": {
+			DetectBinary: "intellij-idea-community",
+			PackageID:    "intellij-idea-community",
+			Unsupported:  []string{"emerge"},
+			Steps:        map[string][]string{"linux": {"sudo snap install intellij-idea-community --classic"}},
+			UndoSteps:    map[string][]string{"linux": {"sudo snap remove intellij-idea-community"}},
+		},
+		"Zsh":  {DetectBinary: "zsh", PackageID: "app-shells/zsh", InstallFunc: installOhMyZsh, Steps: map[string][]string{"apt": {"true"}}},
+		"Node": {DetectBinary: "node", Packages: map[string]string{"linux": "nodejs", "darwin": "node", "windows": "nodejs"}},
+		"Git":  {DetectBinary: "git", PackageID: "git", Packages: all("git")},
+	}
+	tools["Git"].Packages["linux"] = "git"
+
+	problems := strings.Join(checkCatalogCoverage(tools), "\n")
+	for _, want := range []string{
+		"Maven: нет пакета или команд установки для dnf",
+		"Zsh: команды install не используются, установку выполняет hook",
+		"Zsh: для команд install.apt не заданы команды uninstall",
+		`Node: пакет "nodejs" для emerge указан без категории`,
+		`Git: пакет "git" для emerge указан без категории`,
+		"Git: пакет packages.linux не используется",
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("нет проблемы %q в:\n%s", want, problems)
+		}
+	}
+	if strings.Contains(problems, "IntelliJ IDEA") {
+		t.Errorf("рецепт snap для IntelliJ IDEA покрывает все менеджеры Linux:\n%s", problems)
+	}
+}
+
+func TestPackageIDIsUsedInsteadOfDetectBinary(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	maven := Tool{DetectBinary: "mvn", PackageID: "maven", Description: "Maven"}
+
+	if err := executeCommand(context.Background(), "linux", "install", maven); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"sudo apt-get install -y maven"}) {
+		t.Errorf("команды %q", got)
+	}
+
+	maven.PackageID = ""
+	err := executeCommand(context.Background(), "linux", "install", maven)
+	if err == nil || !strings.Contains(err.Error(), "нет пакета для apt") {
+		t.Fatalf("без пакета ошибка %v", err)
+	}
+}
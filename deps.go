@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// toolName возвращает имя инструмента из записи "имя" или "имя@версия"
+func toolName(spec string) string {
+	name, _ := splitToolSpec(spec)
+	return name
+}
+
+// withDependencies добавляет в список недостающие зависимости выбранных инструментов.
+// Зависимости ставятся раньше зависимых, поэтому добавляются в начало списка.
+func withDependencies(ctx context.Context, selected []string, osType string) []string {
+	chosen := make(map[string]bool)
+	for _, spec := range selected {
+		chosen[toolName(spec)] = true
+	}
+
+	var added []string
+	var visit func(name string)
+	visit = func(name string) {
+		for _, dep := range availableTools[name].Requires {
+			if chosen[dep] {
+				continue
+			}
+			chosen[dep] = true
+			visit(dep)
+			if tool, ok := lookupTool(dep); ok && !tool.present(ctx, osType) {
+				added = append(added, dep)
+				fmt.Printf("Добавлена зависимость %s (нужна для %s)\n", dep, name)
+			}
+		}
+	}
+	for _, spec := range selected {
+		visit(toolName(spec))
+	}
+	return append(added, selected...)
+}
+
+// dependents возвращает инструменты каталога, которые зависят от name
+func dependents(name string) []string {
+	var result []string
+	for key, tool := range availableTools {
+		if contains(tool.Requires, name) {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// checkRemovable запрещает удалять инструмент, от которого зависят установленные инструменты,
+// если они не удаляются вместе с ним
+func checkRemovable(ctx context.Context, specs []string, osType string) error {
+	removing := make(map[string]bool)
+	for _, spec := range specs {
+		removing[toolName(spec)] = true
+	}
+
+	var problems []string
+	for _, spec := range specs {
+		name := toolName(spec)
+		var blockers []string
+		for _, dependent := range dependents(name) {
+			if removing[dependent] {
+				continue
+			}
+			if tool, ok := lookupTool(dependent); ok && tool.present(ctx, osType) {
+				blockers = append(blockers, dependent)
+			}
+		}
+		if len(blockers) > 0 {
+			problems = append(problems, fmt.Sprintf("%s нужен для %s", name, strings.Join(blockers, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("удаление невозможно: %s; удалите зависимые инструменты вместе с ним", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// toolGraph — граф зависимостей между выбранными инструментами
+type toolGraph struct {
+	// specs — известные инструменты в исходном порядке, без повторов
+	specs []string
+	// waits — инструменты, которые должны завершиться раньше данного
+	waits map[string][]string
+}
+
+// newToolGraph строит граф для выбранных инструментов. При reverse порядок обратный:
+// зависимый инструмент обрабатывается раньше своей зависимости (удаление).
+func newToolGraph(specs []string, reverse bool) *toolGraph {
+	g := &toolGraph{waits: make(map[string][]string)}
+	selected := make(map[string]bool)
+	for _, spec := range specs {
+		name := toolName(spec)
+		if _, ok := availableTools[name]; !ok || selected[name] {
+			continue
+		}
+		selected[name] = true
+		g.specs = append(g.specs, spec)
+	}
+
+	for _, spec := range g.specs {
+		name := toolName(spec)
+		for _, dep := range availableTools[name].Requires {
+			if !selected[dep] {
+				continue
+			}
+			if reverse {
+				g.waits[dep] = append(g.waits[dep], name)
+			} else {
+				g.waits[name] = append(g.waits[name], dep)
+			}
+		}
+	}
+	return g
+}
+
+// nodeState — состояние инструмента при обходе графа
+type nodeState int
+
+const (
+	nodePending nodeState = iota
+	nodeRunning
+	nodeDone
+	nodeFailed
+)
+
+// run выполняет op для инструментов графа и возвращает результат по каждому из них в исходном
+// порядке. Инструмент запускается, когда завершены все инструменты, которых он ждет, поэтому
+// независимые ветви идут параллельно. После первой ошибки новые инструменты не запускаются,
+// если не задан --keep-going; зависимые от неудавшегося инструмента пропускаются в любом случае.
+// В режиме --dry-run инструменты обрабатываются по одному, чтобы план был упорядочен. После
+// отмены ctx новые инструменты не запускаются, а необработанные отмечаются ошибкой.
+func (g *toolGraph) run(ctx context.Context, op func(Tool) error) []ToolResult {
+	limit := len(g.specs)
+	if dryRun {
+		limit = 1
+	}
+
+	type result struct {
+		name string
+		err  error
+	}
+	done := make(chan result)
+	state := make(map[string]nodeState, len(g.specs))
+	results := make(map[string]ToolResult, len(g.specs))
+	running := 0
+	stopped := false
+
+	for {
+		for changed := !stopped && ctx.Err() == nil; changed; {
+			changed = false
+			for _, spec := range g.specs {
+				name := toolName(spec)
+				if state[name] != nodePending || running >= limit {
+					continue
+				}
+				ready, failedDep := g.ready(name, state)
+				if failedDep != "" {
+					state[name] = nodeFailed
+					results[name] = newToolResult(name, fmt.Errorf("%s пропущен: не выполнена зависимость %s", name, failedDep))
+					changed = true
+					continue
+				}
+				if !ready {
+					continue
+				}
+				tool, _ := lookupTool(spec)
+				state[name] = nodeRunning
+				running++
+				changed = true
+				go func(name string, tool Tool) {
+					done <- result{name: name, err: op(tool)}
+				}(name, tool)
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+		r := <-done
+		running--
+		results[r.name] = newToolResult(r.name, r.err)
+		if results[r.name].Outcome == OutcomeFailed {
+			state[r.name] = nodeFailed
+			stopped = !keepGoing
+		} else {
+			state[r.name] = nodeDone
+		}
+	}
+
+	cycle := false
+	ordered := make([]ToolResult, 0, len(g.specs))
+	for _, spec := range g.specs {
+		name := toolName(spec)
+		if state[name] == nodePending {
+			switch {
+			case ctx.Err() != nil:
+				results[name] = newToolResult(name, fmt.Errorf("%s не обработан: операция прервана", name))
+			case stopped:
+				results[name] = newToolResult(name, skipped("не выполнялось после ошибки, см. --keep-going"))
+			case !cycle:
+				cycle = true
+				results[name] = newToolResult(name, fmt.Errorf("циклическая зависимость между инструментами: %s", name))
+			default:
+				results[name] = newToolResult(name, skipped("не выполнялось из-за циклической зависимости"))
+			}
+		}
+		ordered = append(ordered, results[name])
+	}
+	return ordered
+}
+
+// ready сообщает, завершены ли все инструменты, которых ждет name,
+// и возвращает имя зависимости, завершившейся ошибкой
+func (g *toolGraph) ready(name string, state map[string]nodeState) (bool, string) {
+	ready := true
+	for _, dep := range g.waits[name] {
+		switch state[dep] {
+		case nodeFailed:
+			return false, dep
+		case nodeDone:
+		default:
+			ready = false
+		}
+	}
+	return ready, ""
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func useDependentTools(t *testing.T) {
+	useTools(t, map[string]Tool{
+		"Node.js": {DetectBinary: "node", Description: "Node.js", Packages: map[string]string{"linux": "nodejs"}},
+		"npm":     {DetectBinary: "npm", Description: "npm", Requires: []string{"Node.js"}},
+		"Yarn":    {DetectBinary: "yarn", Description: "Yarn", Requires: []string{"Node.js"}},
+		"jq":      jqTool,
+	})
+}
+
+func TestWithDependenciesAddsMissingPrerequisites(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	useFakeExecutor(t, "apt-get")
+	useDependentTools(t)
+
+	got := withDependencies(context.Background(), []string{"Yarn", "npm"}, "linux")
+	if want := []string{"Node.js", "Yarn", "npm"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("получено %q, ожидалось %q", got, want)
+	}
+}
+
+func TestWithDependenciesSkipsInstalledPrerequisites(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	useFakeExecutor(t, "apt-get", "node")
+	useDependentTools(t)
+
+	if got := withDependencies(context.Background(), []string{"Yarn"}, "linux"); !reflect.DeepEqual(got, []string{"Yarn"}) {
+		t.Errorf("получено %q", got)
+	}
+}
+
+func TestToolGraphOrder(t *testing.T) {
+	useDependentTools(t)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(tool Tool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, tool.DetectBinary)
+		return nil
+	}
+	position := func(command string) int {
+		for i, item := range order {
+			if item == command {
+				return i
+			}
+		}
+		return -1
+	}
+
+	if errs := failures(newToolGraph([]string{"Yarn", "jq", "npm", "Node.js"}, false).run(context.Background(), record)); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if len(order) != 4 || position("node") > position("npm") || position("node") > position("yarn") {
+		t.Errorf("установка в порядке %q", order)
+	}
+
+	order = nil
+	if errs := failures(newToolGraph([]string{"Node.js", "npm", "Yarn"}, true).run(context.Background(), record)); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if position("node") != 2 {
+		t.Errorf("удаление в порядке %q", order)
+	}
+}
+
+func TestCheckRemovableRefusesDependedOnTool(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	useFakeExecutor(t, "apt-get", "node", "npm")
+	useDependentTools(t)
+
+	err := checkRemovable(context.Background(), []string{"Node.js"}, "linux")
+	if err == nil || !strings.Contains(err.Error(), "Node.js нужен для npm") {
+		t.Fatalf("ожидалась ошибка, получено %v", err)
+	}
+	if err := checkRemovable(context.Background(), []string{"Node.js", "npm"}, "linux"); err != nil {
+		t.Errorf("удаление вместе с зависимыми: %v", err)
+	}
+}
+
+func TestCatalogRejectsDependencyCycle(t *testing.T) {
+	catalog, err := parseCatalog([]byte(`
+tools:
+  - {name: a, command: a, stacks: [Frontend], requires: [b]}
+  - {name: b, command: b, stacks: [Frontend], requires: [a]}
+  - {name: c, command: c, stacks: [Frontend], requires: [missing]}
+`), "тест")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = catalog.validate()
+	if err == nil || !strings.Contains(err.Error(), "a → b → a") || !strings.Contains(err.Error(), `неизвестная зависимость "missing"`) {
+		t.Fatalf("ошибка %v", err)
+	}
+}
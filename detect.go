@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Стратегии обнаружения установленного инструмента в порядке проверки
+const (
+	detectPath     = "path"
+	detectPackage  = "package"
+	detectSnap     = "snap"
+	detectApp      = "app"
+	detectWinget   = "winget"
+	detectRegistry = "registry"
+)
+
+// ToolDetect описывает дополнительные признаки установленного инструмента для приложений,
+// которые не кладут исполняемый файл в PATH: snap-пакеты, .app в macOS и программы Windows
+type ToolDetect struct {
+	// Snap — имя snap-пакета
+	Snap string
+	// App — имя пакета приложения macOS, например "IntelliJ IDEA CE.app"
+	App string
+	// Winget — идентификатор пакета winget
+	Winget string
+	// Registry — начало DisplayName в списке установленных программ Windows
+	Registry string
+}
+
+// Detection — способ, которым инструмент был обнаружен
+type Detection struct {
+	// Strategy — сработавшая стратегия: path, package, snap, app, winget или registry
+	Strategy string
+	// Detail — путь к файлу или имя пакета, по которому найден инструмент
+	Detail string
+}
+
+// String возвращает стратегию и подробности для вывода в status
+func (d Detection) String() string {
+	if d.Detail == "" {
+		return d.Strategy
+	}
+	return fmt.Sprintf("%s (%s)", d.Strategy, d.Detail)
+}
+
+// applicationDirs возвращает каталоги, в которых ищутся приложения macOS
+var applicationDirs = func() []string {
+	dirs := []string{"/Applications"}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "Applications"))
+	}
+	return dirs
+}
+
+// detect проверяет стратегии по очереди и возвращает первую сработавшую:
+// исполняемый файл в PATH, запрос к пакетному менеджеру (в том числе brew list --cask
+// и choco list), snap list, пакет .app в macOS, winget list и реестр Windows
+func (t Tool) detect(ctx context.Context, osType string) (Detection, bool) {
+	if t.DetectBinary != "" && isInstalled(ctx, t.DetectBinary, osType) {
+		detection := Detection{Strategy: detectPath}
+		if path, err := executor.LookPath(t.DetectBinary); err == nil {
+			detection.Detail = path
+		}
+		return detection, true
+	}
+
+	if pm, err := detectPackageManager(osType); err == nil {
+		if pkg, err := t.resolvedPackage(osType, pm.Name()); err == nil && pm.IsInstalled(ctx, pkg) {
+			return Detection{Strategy: detectPackage, Detail: pm.Name() + " " + pkg}, true
+		}
+	}
+
+	switch osType {
+	case "linux":
+		if t.Detect.Snap != "" && snapInstalled(ctx, t.Detect.Snap) {
+			return Detection{Strategy: detectSnap, Detail: t.Detect.Snap}, true
+		}
+	case "darwin":
+		if t.Detect.App != "" {
+			if path, ok := findApp(t.Detect.App); ok {
+				return Detection{Strategy: detectApp, Detail: path}, true
+			}
+		}
+	case "windows":
+		if t.Detect.Winget != "" && wingetInstalled(ctx, t.Detect.Winget) {
+			return Detection{Strategy: detectWinget, Detail: t.Detect.Winget}, true
+		}
+		if t.Detect.Registry != "" {
+			if name, ok := registryInstalled(ctx, t.Detect.Registry); ok {
+				return Detection{Strategy: detectRegistry, Detail: name}, true
+			}
+		}
+	}
+
+	log.Printf("%s не обнаружен ни одним способом\n", t.Description)
+	return Detection{}, false
+}
+
+// snapInstalled проверяет snap-пакет через snap list
+func snapInstalled(ctx context.Context, name string) bool {
+	if _, err := executor.LookPath("snap"); err != nil {
+		return false
+	}
+	_, err := executor.Output(ctx, "snap", "list", name)
+	return err == nil
+}
+
+// findApp ищет пакет приложения macOS в каталогах приложений
+func findApp(app string) (string, bool) {
+	for _, dir := range applicationDirs() {
+		path := filepath.Join(dir, app)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// wingetInstalled проверяет пакет по идентификатору через winget list
+func wingetInstalled(ctx context.Context, id string) bool {
+	output, err := executor.Output(ctx, "winget", "list", "--exact", "--id", id, "--accept-source-agreements")
+	return err == nil && strings.Contains(output, id)
+}
+
+// registryInstalled ищет программу в разделах Uninstall реестра и возвращает ее DisplayName
+func registryInstalled(ctx context.Context, displayName string) (string, bool) {
+	script := fmt.Sprintf("Get-ItemProperty "+
+		"'HKLM:\\Software\\Microsoft\\Windows\\CurrentVersion\\Uninstall\\*', "+
+		"'HKLM:\\Software\\WOW6432Node\\Microsoft\\Windows\\CurrentVersion\\Uninstall\\*', "+
+		"'HKCU:\\Software\\Microsoft\\Windows\\CurrentVersion\\Uninstall\\*' -ErrorAction SilentlyContinue | "+
+		"Where-Object { $_.DisplayName -like '%s*' } | Select-Object -First 1 -ExpandProperty DisplayName",
+		strings.ReplaceAll(displayName, "'", "''"))
+	output, err := executor.Output(ctx, "powershell", "-Command", script)
+	name := strings.TrimSpace(output)
+	return name, err == nil && name != ""
+}
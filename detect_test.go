@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var intellijTool = Tool{
+	DetectBinary: "intellij-idea-community",
+	Description:  "IntelliJ IDEA",
+	Packages:     map[string]string{"choco": "intellijidea-community", "brew": "--cask intellij-idea-ce", "linux": "intellij-idea-community"},
+	Steps:        map[string][]string{"linux": {"sudo snap install intellij-idea-community --classic"}},
+	Detect: ToolDetect{
+		Snap:   "intellij-idea-community",
+		App:    "IntelliJ IDEA CE.app",
+		Winget: "JetBrains.IntelliJIDEA.Community",
+	},
+}
+
+func TestDetectStrategies(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("snap", func(t *testing.T) {
+		useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+		fake := useFakeExecutor(t, "apt-get", "snap")
+		if _, found := intellijTool.detect(ctx, "linux"); found {
+			t.Fatal("инструмент без snap-пакета обнаружен")
+		}
+		fake.outputs["snap list intellij-idea-community"] = "intellij-idea-community  2024.2  x1  latest/stable  jetbrains✓  classic\n"
+		if got, found := intellijTool.detect(ctx, "linux"); !found || got.Strategy != detectSnap {
+			t.Errorf("обнаружение %+v, %v", got, found)
+		}
+	})
+
+	t.Run("cask", func(t *testing.T) {
+		useTestPlatform(t, Platform{OS: "darwin", Arch: "arm64"})
+		fake := useFakeExecutor(t, "brew")
+		fake.outputs["sh -c brew list --cask intellij-idea-ce"] = "IntelliJ IDEA CE.app\n"
+		got, found := intellijTool.detect(ctx, "darwin")
+		if !found || got.Strategy != detectPackage || got.Detail != "brew --cask intellij-idea-ce" {
+			t.Errorf("обнаружение %+v, %v", got, found)
+		}
+	})
+
+	t.Run("app", func(t *testing.T) {
+		useTestPlatform(t, Platform{OS: "darwin", Arch: "arm64"})
+		useFakeExecutor(t, "brew")
+		dir := t.TempDir()
+		prev := applicationDirs
+		applicationDirs = func() []string { return []string{dir} }
+		t.Cleanup(func() { applicationDirs = prev })
+
+		if _, found := intellijTool.detect(ctx, "darwin"); found {
+			t.Fatal("отсутствующее приложение обнаружено")
+		}
+		if err := os.Mkdir(filepath.Join(dir, "IntelliJ IDEA CE.app"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		got, found := intellijTool.detect(ctx, "darwin")
+		if !found || got.Strategy != detectApp || got.Detail != filepath.Join(dir, "IntelliJ IDEA CE.app") {
+			t.Errorf("обнаружение %+v, %v", got, found)
+		}
+	})
+
+	t.Run("winget", func(t *testing.T) {
+		useTestPlatform(t, Platform{OS: "windows", Arch: "amd64"})
+		fake := useFakeExecutor(t, "choco")
+		fake.outputs["winget list --exact --id JetBrains.IntelliJIDEA.Community --accept-source-agreements"] = "IntelliJ IDEA Community Edition  JetBrains.IntelliJIDEA.Community  2024.2\n"
+		if got, found := intellijTool.detect(ctx, "windows"); !found || got.Strategy != detectWinget {
+			t.Errorf("обнаружение %+v, %v", got, found)
+		}
+	})
+}
+
+func TestInstallSkipsToolFoundAsSnap(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get", "snap")
+	fake.outputs["snap list intellij-idea-community"] = "intellij-idea-community  2024.2\n"
+
+	err := intellijTool.install(context.Background(), "linux")
+	if result := newToolResult("IntelliJ IDEA", err); result.Outcome != OutcomeSkipped || result.Detail != "уже установлен (snap)" {
+		t.Errorf("результат %+v", result)
+	}
+	if len(fake.runs) != 0 {
+		t.Errorf("повторная установка: %q", fake.commands())
+	}
+}
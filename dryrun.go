@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// dryRun включает режим, в котором команды только записываются в план, но не выполняются
+var dryRun bool
+
+// outputFormat задает формат вывода: text или json
+var outputFormat string
+
+// resultOutput — поток для итогового результата команды (плана, статуса).
+// В режиме --output json обычный вывод перенаправляется в stderr, чтобы stdout содержал только JSON.
+var resultOutput io.Writer = os.Stdout
+
+// plannedCommand — команда, которая была бы выполнена
+type plannedCommand struct {
+	Step    int    `json:"step"`
+	Tool    string `json:"tool,omitempty"`
+	Command string `json:"command"`
+	Shell   string `json:"shell"`
+	Sudo    bool   `json:"sudo"`
+}
+
+// commandRecorder накапливает команды режима --dry-run в порядке выполнения
+type commandRecorder struct {
+	mu       sync.Mutex
+	tool     string
+	commands []plannedCommand
+}
+
+var dryRunPlan commandRecorder
+
+// begin отмечает начало работы с инструментом; следующие команды относятся к нему.
+// В режиме --dry-run инструменты обрабатываются последовательно, поэтому отметка однозначна.
+func (r *commandRecorder) begin(tool string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tool = tool
+}
+
+// record добавляет команду в план
+func (r *commandRecorder) record(command, osType string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	shell := "sh"
+	if osType == "windows" {
+		shell = "powershell"
+	}
+	r.commands = append(r.commands, plannedCommand{
+		Step:    len(r.commands) + 1,
+		Tool:    r.tool,
+		Command: command,
+		Shell:   shell,
+		Sudo:    strings.HasPrefix(command, "sudo ") || strings.Contains(command, "| sudo "),
+	})
+}
+
+// print выводит план в выбранном формате
+func (r *commandRecorder) print(w io.Writer, format string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			DryRun   bool             `json:"dry_run"`
+			Commands []plannedCommand `json:"commands"`
+		}{true, append([]plannedCommand{}, r.commands...)})
+	}
+
+	if len(r.commands) == 0 {
+		fmt.Fprintln(w, "План пуст: выполнять нечего.")
+		return nil
+	}
+	fmt.Fprintln(w, "План выполнения (команды не запускались):")
+	for _, c := range r.commands {
+		tool := ""
+		if c.Tool != "" {
+			tool = "[" + c.Tool + "] "
+		}
+		fmt.Fprintf(w, "%3d. %s%s\n", c.Step, tool, c.Command)
+	}
+	return nil
+}
+
+// setupOutput проверяет формат вывода и при необходимости перенаправляет обычный вывод
+func setupOutput() error {
+	switch outputFormat {
+	case "text":
+	case "json":
+		resultOutput = os.Stdout
+		os.Stdout = os.Stderr
+	default:
+		return fmt.Errorf("неизвестный формат вывода %q, допустимые: text, json", outputFormat)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Executor запускает внешние команды. Вся работа с процессами идет через него,
+// чтобы логику установки можно было проверять без изменения системы.
+// Отмена ctx завершает команду вместе со всеми ее дочерними процессами.
+type Executor interface {
+	// Run выполняет команду оболочки (sh или PowerShell), транслируя ее вывод в лог
+	Run(ctx context.Context, command, osType string) error
+	// Output выполняет программу и возвращает ее stdout
+	Output(ctx context.Context, name string, args ...string) (string, error)
+	// LookPath ищет исполняемый файл в PATH
+	LookPath(file string) (string, error)
+}
+
+// executor — исполнитель, используемый программой
+var executor Executor = systemExecutor{}
+
+// killDelay — сколько ждать завершения процессов команды после сигнала, прежде чем убить ее
+const killDelay = 5 * time.Second
+
+// systemExecutor выполняет команды в текущей системе
+type systemExecutor struct{}
+
+func (systemExecutor) Run(ctx context.Context, command, osType string) error {
+	cmd := exec.CommandContext(ctx, shellFor(osType), shellFlag(osType), command)
+	configureCancel(cmd)
+
+	// Выводим команду в реальном времени, сохраняя последние строки для разбора ошибки
+	tail := &outputTail{}
+	stdout := &logWriter{name: "stdout", tail: tail}
+	stderr := &logWriter{name: "stderr", tail: tail}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	stdout.flush()
+	stderr.flush()
+	if err != nil {
+		return &commandFailure{err: err, output: tail.String()}
+	}
+	return nil
+}
+
+// commandFailure — ошибка команды вместе с последними строками ее вывода
+type commandFailure struct {
+	err    error
+	output string
+}
+
+func (e *commandFailure) Error() string {
+	return e.err.Error()
+}
+
+func (e *commandFailure) Unwrap() error {
+	return e.err
+}
+
+// outputTailLines — сколько последних строк вывода хранится для разбора ошибки
+const outputTailLines = 50
+
+// outputTail хранит последние строки stdout и stderr команды
+type outputTail struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (t *outputTail) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, line)
+	if len(t.lines) > outputTailLines {
+		t.lines = t.lines[len(t.lines)-outputTailLines:]
+	}
+}
+
+func (t *outputTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.lines, "\n")
+}
+
+func (systemExecutor) Output(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	configureCancel(cmd)
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+func (systemExecutor) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+// logWriter построчно пишет вывод команды в лог
+type logWriter struct {
+	mu   sync.Mutex
+	name string
+	buf  bytes.Buffer
+	tail *outputTail
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Неполную строку оставляем до следующей записи
+			w.buf.WriteString(line)
+			break
+		}
+		log.Printf("%s: %s", w.name, line)
+		w.tail.add(strings.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+// flush выводит остаток вывода без перевода строки
+func (w *logWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() > 0 {
+		log.Printf("%s: %s\n", w.name, w.buf.String())
+		w.tail.add(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+// shellFor возвращает оболочку для выполнения команд в ОС
+func shellFor(osType string) string {
+	if osType == "windows" {
+		return "powershell"
+	}
+	return "sh"
+}
+
+// shellFlag возвращает флаг оболочки для передачи команды
+func shellFlag(osType string) string {
+	if osType == "windows" {
+		return "-Command"
+	}
+	return "-c"
+}
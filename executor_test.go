@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRun — команда, переданная в fakeExecutor.Run
+type fakeRun struct {
+	Command string
+	OS      string
+}
+
+// fakeExecutor записывает команды вместо выполнения
+type fakeExecutor struct {
+	mu sync.Mutex
+	// binaries — исполняемые файлы, которые «есть» в PATH
+	binaries map[string]bool
+	// outputs — вывод программ по строке "имя аргументы..."
+	outputs map[string]string
+	// failOn — подстроки команд Run, которые завершаются ошибкой
+	failOn []string
+	// failOutput — вывод упавшей команды, failTimes — сколько раз она падает (0 — всегда)
+	failOutput string
+	failTimes  int
+	failures   int
+	// paths — пути, которые LookPath возвращает вместо /usr/bin/имя
+	paths map[string]string
+	// onRun вызывается перед записью команды вне блокировки, например чтобы имитировать долгую команду
+	onRun func(command string)
+	runs  []fakeRun
+}
+
+func (f *fakeExecutor) Run(ctx context.Context, command, osType string) error {
+	if f.onRun != nil {
+		f.onRun(command)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.runs = append(f.runs, fakeRun{Command: command, OS: osType})
+	for _, pattern := range f.failOn {
+		if strings.Contains(command, pattern) && (f.failTimes == 0 || f.failures < f.failTimes) {
+			f.failures++
+			return &commandFailure{err: errors.New("exit status 100"), output: f.failOutput}
+		}
+	}
+	return nil
+}
+
+func (f *fakeExecutor) Output(ctx context.Context, name string, args ...string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := strings.TrimSpace(name + " " + strings.Join(args, " "))
+	if output, ok := f.outputs[key]; ok {
+		return output, nil
+	}
+	return "", errors.New("exit status 1")
+}
+
+func (f *fakeExecutor) LookPath(file string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if path, ok := f.paths[file]; ok {
+		return path, nil
+	}
+	if f.binaries[file] {
+		return "/usr/bin/" + file, nil
+	}
+	return "", errors.New("executable file not found in $PATH")
+}
+
+// commands возвращает строки выполненных команд
+func (f *fakeExecutor) commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var commands []string
+	for _, run := range f.runs {
+		commands = append(commands, run.Command)
+	}
+	return commands
+}
+
+// useFakeExecutor подменяет исполнителя и окружение на время теста.
+// Программа считается запущенной не от root, /etc/os-release отсутствует,
+// файл состояния пишется во временный каталог.
+func useFakeExecutor(t *testing.T, binaries ...string) *fakeExecutor {
+	t.Helper()
+
+	fake := &fakeExecutor{binaries: make(map[string]bool), outputs: make(map[string]string)}
+	for _, binary := range binaries {
+		fake.binaries[binary] = true
+	}
+
+	prevExecutor, prevIsRoot, prevRelease, prevSources := executor, isRoot, osReleasePath, sourcesRoot
+	executor = fake
+	isRoot = func() bool { return false }
+	osReleasePath = t.TempDir() + "/os-release"
+	// Репозитории и ключи ищутся в пустом каталоге, а не в /etc машины, где запущены тесты
+	sourcesRoot = t.TempDir()
+	// Индексы пакетов считаются свежими, чтобы тесты видели только команды действий;
+	// обновление индекса проверяется отдельно через useStaleIndexes
+	packageRefreshes.Lock()
+	packageRefreshes.once = make(map[string]*sync.Once)
+	for _, name := range packageManagerNames() {
+		done := &sync.Once{}
+		done.Do(func() {})
+		packageRefreshes.once[name] = done
+	}
+	packageRefreshes.Unlock()
+	// Состояние установок пишется во временный каталог, а не в домашний
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Cleanup(func() {
+		executor, isRoot, osReleasePath, sourcesRoot = prevExecutor, prevIsRoot, prevRelease, prevSources
+	})
+	return fake
+}
+
+// useStaleIndexes сбрасывает отметки об обновлении индексов, как в начале нового запуска
+func useStaleIndexes(t *testing.T) {
+	t.Helper()
+	packageRefreshes.Lock()
+	packageRefreshes.once = make(map[string]*sync.Once)
+	packageRefreshes.Unlock()
+}
+
+// useTestPlatform задает сведения о платформе на время теста
+func useTestPlatform(t *testing.T, p Platform) {
+	t.Helper()
+	platformOnce.Do(func() {})
+	prev := platform
+	platform = p
+	t.Cleanup(func() { platform = prev })
+}
+
+// useTools подменяет каталог инструментов на время теста
+func useTools(t *testing.T, tools map[string]Tool) {
+	t.Helper()
+	prev := availableTools
+	availableTools = tools
+	t.Cleanup(func() { availableTools = prev })
+}
+
+func TestFakeExecutorRecordsCommands(t *testing.T) {
+	fake := useFakeExecutor(t, "git")
+	fake.failOn = []string{"broken"}
+
+	if err := runCommand(context.Background(), "echo ok", "linux"); err != nil {
+		t.Fatalf("runCommand: %v", err)
+	}
+	if err := runCommand(context.Background(), "broken step", "linux"); err == nil {
+		t.Fatal("ожидалась ошибка для команды broken step")
+	}
+	if got := fake.commands(); len(got) != 2 || got[0] != "echo ok" {
+		t.Fatalf("записаны команды %q", got)
+	}
+	if !isInstalled(context.Background(), "git", "linux") || isInstalled(context.Background(), "jq", "linux") {
+		t.Fatal("isInstalled должен использовать LookPath исполнителя")
+	}
+}
@@ -0,0 +1,58 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// configureCancel оставляет команду в группе процессов терминала: sudo должен иметь доступ
+// к терминалу, чтобы запросить пароль, а отдельная группа получила бы SIGTTOU и зависла.
+// При отмене контекста сигнал, прервавший программу (или SIGTERM при тайм-ауте), получает
+// все дерево процессов команды: оболочка, sudo, apt и запущенные ими процессы.
+func configureCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return signalTree(cmd.Process.Pid, interruptSignal())
+	}
+	cmd.WaitDelay = killDelay
+}
+
+// signalTree отправляет сигнал процессу pid и всем его потомкам.
+// Потомки собираются заранее: после сигнала оболочке они перешли бы к init и потерялись.
+func signalTree(pid int, sig syscall.Signal) error {
+	tree := []int{pid}
+	children := processChildren()
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+	for _, child := range tree[1:] {
+		// Потомок мог уже завершиться сам
+		_ = syscall.Kill(child, sig)
+	}
+	return syscall.Kill(pid, sig)
+}
+
+// processChildren возвращает дочерние процессы каждого процесса системы по выводу ps.
+// Если ps недоступен, список пуст и сигнал получит только сама команда.
+func processChildren() map[int][]int {
+	children := make(map[int][]int)
+	output, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=").Output()
+	if err != nil {
+		return children
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 == nil && err2 == nil {
+			children[ppid] = append(children[ppid], pid)
+		}
+	}
+	return children
+}
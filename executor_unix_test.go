@@ -0,0 +1,34 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSystemExecutorStopsProcessTree(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// Дочерний sleep держит stdout открытым: без остановки всего дерева Run ждал бы его до killDelay
+	start := time.Now()
+	err := systemExecutor{}.Run(ctx, "sleep 30 & sleep 30; wait", "linux")
+	if err == nil {
+		t.Fatal("ожидалась ошибка после тайм-аута")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("команда остановлена через %v", elapsed)
+	}
+}
+
+func TestSystemExecutorKeepsTerminalProcessGroup(t *testing.T) {
+	// Команда в отдельной группе не смогла бы читать пароль sudo с терминала
+	command := fmt.Sprintf(`test "$(ps -o pgid= -p $$ | tr -d ' ')" = %d`, syscall.Getpgrp())
+	if err := (systemExecutor{}).Run(context.Background(), command, "linux"); err != nil {
+		t.Errorf("команда запущена в другой группе процессов: %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+import "os/exec"
+
+// configureCancel задает время ожидания завершения команды после отмены.
+// В Windows нет сигналов POSIX, поэтому процесс завершается через TerminateProcess.
+func configureCancel(cmd *exec.Cmd) {
+	cmd.WaitDelay = killDelay
+}
@@ -1,71 +1,59 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
-	"log"
 )
 
+var installFlags selectionFlags
+
 var installCmd = &cobra.Command{
-	Use:   "install",
-	Short: "Установить инструменты для выбранного стека",
-	Run:   installRun,
+	Use:     "install [инструменты...]",
+	Short:   "Установить инструменты для выбранного стека",
+	Example: "  dev-installer install --stack Golang --ide GoLand git docker jq",
+	RunE:    installRun,
+}
+
+func init() {
+	installCmd.Flags().StringVar(&installFlags.stack, "stack", "", "стек разработки (Frontend, Java/Kotlin, Golang, Python, Essential Tools)")
+	installCmd.Flags().StringSliceVar(&installFlags.ide, "ide", nil, "IDE для установки (можно указать несколько через запятую)")
+	installCmd.Flags().BoolVar(&atomicInstall, "atomic", false, "при ошибке отменить все изменения этого запуска в обратном порядке")
 }
 
-func installRun(cmd *cobra.Command, args []string) {
+func installRun(cmd *cobra.Command, args []string) error {
+	if err := checkAtomic(); err != nil {
+		return err
+	}
 	osType := detectOS()
 	fmt.Printf("Обнаруженная ОС: %s\n", osType)
 
-	stackStr := selectStack()
-	stack := StringToStack(stackStr)
-	ide := selectIDE(stack)
-	additionalTools := selectStackTools(stackStr)
-
-	err := installStack(stack, ide, additionalTools, osType)
+	stack, err := resolveStack(installFlags, args)
 	if err != nil {
-		fmt.Printf("Ошибка установки: %v\n", err)
-	} else {
-		fmt.Println("Установка завершена успешно.")
+		return err
 	}
-}
-
-func selectIDE(stack Stack) []string {
-	var options []string
-
-	switch stack {
-	case FrontendStack:
-		options = []string{"Visual Studio Code", "WebStorm", "Sublime Text"}
-	case JavaKotlinStack:
-		options = []string{"IntelliJ IDEA", "Eclipse", "NetBeans"}
-	case GolangStack:
-		options = []string{"Visual Studio Code", "GoLand", "Sublime Text"}
-	case PythonStack:
-		options = []string{"PyCharm", "Visual Studio Code", "Sublime Text"}
-	default:
-		options = []string{"Visual Studio Code", "Sublime Text"}
-	}
-
-	prompt := promptui.Select{
-		Label: "Выберите IDE",
-		Items: append([]string{"[Выбрать все]"}, options...),
-		Size:  len(options) + 1,
-		Templates: &promptui.SelectTemplates{
-			Label:    "{{ . }}",
-			Active:   "\u25B6 {{ . | cyan }}",
-			Inactive: "  {{ . }}",
-			Selected: "\u2714 {{ . | green }}",
-		},
+	ide, err := resolveIDE(cmd.Context(), installFlags, stack, args)
+	if err != nil {
+		return err
 	}
-
-	_, result, err := prompt.Run()
+	additionalTools, err := resolveTools(cmd.Context(), stack, args)
 	if err != nil {
-		log.Fatalf("Ошибка при выборе IDE: %v", err)
+		return err
 	}
 
-	if result == "[Выбрать все]" {
-		return options
+	if interactive(args) {
+		if err := confirmSelection(cmd.Context(), ActionInstall, stack, ide, additionalTools, osType); err != nil {
+			return err
+		}
 	}
+	return performAction(cmd.Context(), ActionInstall, stack, ide, additionalTools, osType)
+}
+
+// ideOptions возвращает IDE, предлагаемые для стека
+func ideOptions(stack Stack) []string {
+	return ideByStack[string(stack)]
+}
 
-	return []string{result}
+func selectIDE(ctx context.Context, stack Stack) []string {
+	return multiSelect(ctx, "Выберите IDE", ideOptions(stack), detectOS())
 }
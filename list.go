@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var listStack string
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Показать стеки и доступные в них инструменты",
+	RunE:  listRun,
+}
+
+func init() {
+	listCmd.Flags().StringVar(&listStack, "stack", "", "показать только указанный стек")
+}
+
+func listRun(cmd *cobra.Command, args []string) error {
+	stacks := allStacks
+	if listStack != "" {
+		stack, err := parseStack(listStack)
+		if err != nil {
+			return err
+		}
+		stacks = []Stack{stack}
+	}
+
+	for _, stack := range stacks {
+		fmt.Printf("%s\n", stack)
+		fmt.Printf("  IDE:          %s\n", strings.Join(ideOptions(stack), ", "))
+		fmt.Printf("  Инструменты:  %s\n", strings.Join(toolsByStack[string(stack)], ", "))
+	}
+	return nil
+}
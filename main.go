@@ -1,101 +1,111 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 	"log"
 	"os"
-	"os/exec"
-	"sync"
+	"strings"
 )
 
-// availableTools содержит все доступные инструменты
-var availableTools = map[string]Tool{
-	"Node.js":            {"node", "Node.js", nil},
-	"npm":                {"npm", "npm", nil},
-	"Yarn":               {"yarn", "Yarn", nil},
-	"Visual Studio Code": {"code", "Visual Studio Code", nil},
-	"WebStorm":           {"webstorm", "WebStorm", nil},
-	"Sublime Text":       {"sublime-text", "Sublime Text", nil},
-	"OpenJDK":            {"java", "OpenJDK", nil},
-	"Maven":              {"mvn", "Maven", nil},
-	"Gradle":             {"gradle", "Gradle", nil},
-	"IntelliJ IDEA":      {"intellij", "IntelliJ IDEA", nil},
-	"Eclipse":            {"eclipse", "Eclipse", nil},
-	"NetBeans":           {"netbeans", "NetBeans", nil},
-	"Golang":             {"go", "Golang", nil},
-	"Python 3":           {"python3", "Python 3", nil},
-	"Pip":                {"pip3", "Pip", nil},
-	"Virtualenv":         {"virtualenv", "Virtualenv", nil},
-	"Git":                {"git", "Git", nil},
-	"Docker":             {"docker", "Docker", nil},
-	"Curl":               {"curl", "Curl", nil},
-	"Zsh":                {"zsh", "Zsh", installOhMyZsh},
-	"jq":                 {"jq", "jq", nil},
-	"Postman":            {"postman", "Postman", nil},
-	"Neovim":             {"nvim", "Neovim", installAstroNvim},
-	"GoLand":             {"goland", "GoLand", nil},
-	"PyCharm":            {"pycharm", "PyCharm", nil},
-}
+// availableTools содержит все доступные инструменты; заполняется из каталога
+var availableTools map[string]Tool
+
+// toolsByStack содержит инструменты, предлагаемые для каждого стека
+var toolsByStack map[string][]string
+
+// ideByStack содержит IDE, предлагаемые для каждого стека
+var ideByStack map[string][]string
 
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "dev-installer",
 		Short: "Утилита для установки инструментов разработчика",
 		Long:  `Эта программа позволяет устанавливать, обновлять и удалять инструменты для различных стеков разработки.`,
-		Run:   run,
-	}
-
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		RunE:  run,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupOutput(); err != nil {
+				return err
+			}
+			if err := validateBackends(viaBackends); err != nil {
+				return err
+			}
+			if totalTimeout > 0 {
+				ctx, cancel := context.WithTimeout(cmd.Context(), totalTimeout)
+				cmd.SetContext(ctx)
+				releaseTimeout = cancel
+			}
+			return loadCatalog()
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			if dryRun {
+				return dryRunPlan.print(resultOutput, outputFormat)
+			}
+			return nil
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "не задавать вопросов: завершиться с ошибкой, если выбор не указан флагами")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "только показать команды, которые будут выполнены")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "формат вывода: text или json")
+	rootCmd.PersistentFlags().StringSliceVar(&viaBackends, "via", nil, "менеджеры версий по приоритету: "+strings.Join(versionManagerNames(), ", ")+" или system")
+	rootCmd.PersistentFlags().DurationVar(&totalTimeout, "timeout", 0, "ограничение времени всей операции, например 30m (0 — без ограничения)")
+	rootCmd.PersistentFlags().DurationVar(&stepTimeout, "step-timeout", 0, "ограничение времени одной команды, например 10m (0 — без ограничения)")
+	rootCmd.PersistentFlags().BoolVar(&keepGoing, "keep-going", false, "не останавливаться на ошибке: обработать все выбранные инструменты и вывести итог")
+	rootCmd.PersistentFlags().IntVar(&retryAttempts, "retries", retryAttempts, "сколько раз повторять команду после временной сетевой ошибки или занятой блокировки")
+	rootCmd.PersistentFlags().DurationVar(&retryDelay, "retry-delay", retryDelay, "пауза перед первым повтором, затем удваивается")
+	rootCmd.Flags().BoolVar(&forceUninstall, "force", false, "при удалении удалять и инструменты, установленные не через DevOrchestrator")
+	rootCmd.AddCommand(installCmd, updateCmd, uninstallCmd, listCmd, statusCmd, applyCmd, platformCmd, catalogCmd)
+
+	// Ctrl-C и SIGTERM отменяют контекст: запущенные команды останавливаются вместе с дочерними процессами
+	ctx, stop := withSignals(context.Background())
+	err := rootCmd.ExecuteContext(ctx)
+	releaseTimeout()
+	stop()
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run(cmd *cobra.Command, args []string) {
+func run(cmd *cobra.Command, args []string) error {
 	// Определяем ОС
 	osType := detectOS()
 	fmt.Printf("Обнаруженная ОС: %s\n", osType)
 
+	if assumeYes {
+		return errPromptDisabled("не указано действие", "используйте команды install, update или uninstall")
+	}
+	ctx := cmd.Context()
+
 	// Шаг 1: Выбор действия
 	action := selectAction()
 
 	// Шаг 2: Выбор стека разработки
-	stackStr := selectStack()
-	stack := StringToStack(stackStr)
+	stack := StringToStack(selectStack())
 
 	// Шаг 3: Выбор инструментов
-	var tools []string
 	var ide []string
-
-	if action == "Установить" {
-		ide = selectIDE(stack)
-	}
-	tools = selectStackTools(stackStr)
-
-	// Выполнение выбранного действия
-	var err error
-	switch action {
-	case "Установить":
-		err = performInstall(stack, ide, tools, osType)
-	case "Обновить":
-		err = performUpdate(tools, osType)
-	case "Удалить":
-		err = performUninstall(tools, osType)
+	if action == ActionInstall {
+		ide = selectIDE(ctx, stack)
 	}
+	tools := selectStackTools(ctx, string(stack))
 
-	if err != nil {
-		fmt.Printf("Ошибка: %v\n", err)
-	} else {
-		fmt.Printf("%s: операция выполнена успешно.\n", action)
+	if err := confirmSelection(ctx, action, stack, ide, tools, osType); err != nil {
+		return err
 	}
+	return performAction(ctx, action, stack, ide, tools, osType)
 }
 
-func selectAction() string {
+func selectAction() Action {
 	prompt := promptui.Select{
 		Label: "Выберите действие",
-		Items: []string{"Установить", "Обновить", "Удалить"},
+		Items: []Action{ActionInstall, ActionUpdate, ActionUninstall},
 		Size:  3,
 	}
 
@@ -104,86 +114,102 @@ func selectAction() string {
 		log.Fatalf("Ошибка при выборе действия: %v", err)
 	}
 
-	return result
-}
-
-func performInstall(stack Stack, ide []string, tools []string, osType string) error {
-	return installStack(stack, ide, tools, osType)
+	return Action(result)
 }
 
-func performUpdate(tools []string, osType string) error {
-	var wg sync.WaitGroup
-	errorsCh := make(chan error, len(tools))
-
-	for _, toolName := range tools {
-		if tool, ok := availableTools[toolName]; ok {
-			wg.Add(1)
-			go func(tool Tool) {
-				defer wg.Done()
-				if err := tool.update(osType); err != nil {
-					errorsCh <- fmt.Errorf("ошибка обновления %s: %v", tool.Description, err)
-				}
-			}(tool)
-		}
+// performAction выполняет действие над выбранными инструментами.
+// Это общая точка входа для интерактивного режима и всех подкоманд.
+func performAction(ctx context.Context, action Action, stack Stack, ide []string, tools []string, osType string) error {
+	var err error
+	switch action {
+	case ActionInstall:
+		err = performInstall(ctx, stack, ide, tools, osType)
+	case ActionUpdate:
+		err = performUpdate(ctx, tools, osType)
+	case ActionUninstall:
+		err = performUninstall(ctx, tools, osType)
+	default:
+		err = fmt.Errorf("неизвестное действие: %s", action)
 	}
 
-	wg.Wait()
-	close(errorsCh)
-
-	var errors []error
-	for err := range errorsCh {
-		errors = append(errors, err)
+	if err != nil {
+		return err
 	}
-
-	if len(errors) > 0 {
-		return fmt.Errorf("произошли ошибки при обновлении: %v", errors)
+	if dryRun {
+		fmt.Printf("%s: план сформирован, команды не выполнялись.\n", action)
+		return nil
 	}
+	fmt.Printf("%s: операция выполнена успешно.\n", action)
 	return nil
 }
 
-func performUninstall(tools []string, osType string) error {
-	var wg sync.WaitGroup
-	errorsCh := make(chan error, len(tools))
+func performInstall(ctx context.Context, stack Stack, ide []string, tools []string, osType string) error {
+	return installStack(ctx, stack, ide, withDependencies(ctx, tools, osType), osType)
+}
 
-	for _, toolName := range tools {
-		if tool, ok := availableTools[toolName]; ok {
-			wg.Add(1)
-			go func(tool Tool) {
-				defer wg.Done()
-				if err := tool.uninstall(osType); err != nil {
-					errorsCh <- fmt.Errorf("ошибка удаления %s: %v", tool.Description, err)
-				}
-			}(tool)
+func performUpdate(ctx context.Context, tools []string, osType string) error {
+	return runForTools(ctx, tools, false, func(tool Tool) error {
+		if err := tool.update(ctx, osType); err != nil {
+			return fmt.Errorf("ошибка обновления %s: %w", tool.Description, err)
 		}
-	}
+		return nil
+	}, "произошли ошибки при обновлении")
+}
 
-	wg.Wait()
-	close(errorsCh)
+func performUninstall(ctx context.Context, tools []string, osType string) error {
+	if err := checkRemovable(ctx, tools, osType); err != nil {
+		return err
+	}
+	if err := checkProvenance(ctx, tools, osType); err != nil {
+		return err
+	}
+	return runForTools(ctx, tools, true, func(tool Tool) error {
+		tool = installedAs(tool)
+		var err error
+		if record, ok, _ := installRecord(tool.Name); ok && record.Backend == "hook" {
+			// DevOrchestrator выполнил только встроенную функцию: удаляются ее файлы, а не пакет
+			err = tool.uninstallHook(ctx, record, osType)
+		} else {
+			err = tool.uninstall(ctx, osType)
+		}
+		// Инструмент могли удалить вручную: репозитории и ключи, подключенные при установке, все равно убираются
+		if err != nil && !errors.Is(err, errNotInstalled) {
+			return fmt.Errorf("ошибка удаления %s: %w", tool.Description, err)
+		}
+		if purgeUninstall {
+			if err := purgeTool(ctx, tool, osType); err != nil {
+				return err
+			}
+		}
+		if !dryRun {
+			forgetInstall(tool)
+		}
+		return err
+	}, "произошли ошибки при удалении")
+}
 
-	var errors []error
-	for err := range errorsCh {
-		errors = append(errors, err)
+// runForTools выполняет операцию для каждого инструмента с учетом зависимостей, выводит итоговую
+// таблицу и возвращает ошибку, если хотя бы один инструмент не обработан. Независимые инструменты
+// обрабатываются параллельно; при reverse зависимые обрабатываются раньше своих зависимостей.
+func runForTools(ctx context.Context, tools []string, reverse bool, op func(Tool) error, summary string) error {
+	results := newToolGraph(tools, reverse).run(ctx, op)
+	// В режиме --dry-run итог — это план команд, его выводит корневая команда
+	if !dryRun && len(results) > 0 {
+		if err := printResults(resultOutput, outputFormat, results); err != nil {
+			return err
+		}
 	}
-
-	if len(errors) > 0 {
-		return fmt.Errorf("произошли ошибки при удалении: %v", errors)
+	if errors := failures(results); len(errors) > 0 {
+		return fmt.Errorf("%s: %v", summary, errors)
 	}
 	return nil
 }
 
 func selectStack() string {
-	stacks := []string{
-		"Frontend",
-		"Java/Kotlin",
-		"Golang",
-		"Python",
-		"Essential Tools",
-	}
-
 	prompt := promptui.Select{
 		Label: "Выберите стек разработки",
-		Items: stacks,
-		Size:  len(stacks),
+		Items: allStacks,
+		Size:  len(allStacks),
 	}
 
 	_, result, err := prompt.Run()
@@ -194,104 +220,21 @@ func selectStack() string {
 	return result
 }
 
-func selectStackTools(stack string) []string {
-	toolsByStack := map[string][]string{
-		"Frontend": {
-			"Git",
-			"Node.js",
-			"npm",
-			"Yarn",
-			"Docker",
-			"Curl",
-			"Zsh",
-			"jq",
-			"Postman",
-			"Neovim",
-		},
-		"Java/Kotlin": {
-			"Git",
-			"OpenJDK",
-			"Maven",
-			"Gradle",
-			"Docker",
-			"Curl",
-			"Zsh",
-			"jq",
-			"Postman",
-			"Neovim",
-		},
-		"Golang": {
-			"Git",
-			"Golang",
-			"Docker",
-			"Curl",
-			"Zsh",
-			"jq",
-			"Postman",
-			"Neovim",
-		},
-		"Python": {
-			"Git",
-			"Python 3",
-			"Pip",
-			"Virtualenv",
-			"Docker",
-			"Curl",
-			"Zsh",
-			"jq",
-			"Postman",
-			"Neovim",
-		},
-		"Essential Tools": {
-			"Git",
-			"Docker",
-			"Curl",
-			"Zsh",
-			"jq",
-			"Postman",
-			"Neovim",
-		},
-	}
-
-	prompt := promptui.Select{
-		Label: "Выберите инструменты (Space для выбора, Enter для подтверждения)",
-		Items: append([]string{"[Выбрать все]"}, toolsByStack[stack]...),
-		Size:  len(toolsByStack[stack]) + 1,
-		Templates: &promptui.SelectTemplates{
-			Label:    "{{ . }}",
-			Active:   "\u25B6 {{ . | cyan }}",
-			Inactive: "  {{ . }}",
-			Selected: "\u2714 {{ . | green }}",
-		},
-	}
-
-	_, result, err := prompt.Run()
-	if err != nil {
-		log.Fatalf("Ошибка при выборе инструментов: %v", err)
-	}
-
-	if result == "[Выбрать все]" {
-		return toolsByStack[stack]
-	}
-
-	return []string{result}
+func selectStackTools(ctx context.Context, stack string) []string {
+	return multiSelect(ctx, "Выберите инструменты", toolsByStack[stack], detectOS())
 }
 
 // Специальные функции установки
-func installOhMyZsh() error {
-	cmd := exec.Command("sh", "-c", "curl -fsSL https://raw.githubusercontent.com/ohmyzsh/ohmyzsh/master/tools/install.sh | sh")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ошибка установки Oh My Zsh: %v, вывод: %s", err, string(output))
+func installOhMyZsh(ctx context.Context) error {
+	if err := runCommand(ctx, "curl -fsSL https://raw.githubusercontent.com/ohmyzsh/ohmyzsh/master/tools/install.sh | sh", detectOS()); err != nil {
+		return fmt.Errorf("ошибка установки Oh My Zsh: %v", err)
 	}
 	return nil
 }
 
-func installAstroNvim() error {
-	cmd := exec.Command("git", "clone", "https://github.com/AstroNvim/AstroNvim", "~/.config/nvim")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ошибка установки AstroNvim: %v, вывод: %s", err, string(output))
+func installAstroNvim(ctx context.Context) error {
+	if err := runCommand(ctx, "git clone https://github.com/AstroNvim/AstroNvim ~/.config/nvim", detectOS()); err != nil {
+		return fmt.Errorf("ошибка установки AstroNvim: %v", err)
 	}
 	return nil
 }
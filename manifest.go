@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultManifestFile — имя манифеста, который ищется в текущем каталоге
+const defaultManifestFile = "devorchestrator.yaml"
+
+// Manifest описывает желаемое состояние рабочего окружения команды
+type Manifest struct {
+	Stack  string         `yaml:"stack"`
+	IDE    []ManifestTool `yaml:"ide"`
+	Tools  []ManifestTool `yaml:"tools"`
+	Remove []string       `yaml:"remove"`
+	// Update включает обновление уже установленных инструментов
+	Update bool `yaml:"update"`
+	// Via — предпочитаемые менеджеры версий, как во флаге --via
+	Via []string `yaml:"via"`
+}
+
+// ManifestTool — инструмент в манифесте с необязательным ограничением версии.
+// В YAML его можно записать строкой ("Git") или объектом ({name: Golang, version: ">=1.22"}).
+type ManifestTool struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+// UnmarshalYAML поддерживает краткую запись инструмента строкой
+func (t *ManifestTool) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		t.Name = value.Value
+		return nil
+	}
+	type plain ManifestTool
+	return value.Decode((*plain)(t))
+}
+
+// PlanStep — одно действие плана применения манифеста
+type PlanStep struct {
+	Action  Action
+	Tool    string
+	Version string
+	// Reason поясняет, почему шаг попал в план
+	Reason string
+}
+
+// Plan — упорядоченный список действий для приведения машины к манифесту
+type Plan struct {
+	Stack   Stack
+	Install []PlanStep
+	IDE     []PlanStep
+	Update  []PlanStep
+	Remove  []PlanStep
+	// Unchanged содержит инструменты, которые уже в нужном состоянии
+	Unchanged []string
+}
+
+// loadManifest читает и проверяет манифест из файла
+func loadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения манифеста: %v", err)
+	}
+	return parseManifest(data)
+}
+
+// parseManifest разбирает манифест и сверяет имена инструментов с каталогом
+func parseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("ошибка разбора манифеста: %v", err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// validate проверяет стек и приводит имена инструментов к ключам availableTools
+func (m *Manifest) validate() error {
+	var problems []string
+
+	if m.Stack != "" {
+		stack, err := parseStack(m.Stack)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			m.Stack = string(stack)
+		}
+	}
+
+	seen := make(map[string]string)
+	check := func(section, name string) string {
+		key, ok := lookupToolName(name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: неизвестный инструмент %q", section, name))
+			return name
+		}
+		if prev, dup := seen[key]; dup {
+			problems = append(problems, fmt.Sprintf("%s: инструмент %q уже указан в разделе %s", section, key, prev))
+		}
+		seen[key] = section
+		return key
+	}
+
+	checkVersion := func(entry ManifestTool) {
+		if entry.Version == "" {
+			return
+		}
+		if _, err := parseConstraint(entry.Version); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", entry.Name, err))
+			return
+		}
+		// Версию неизвестного инструмента проверять не с чем, о нем уже сообщил check
+		if tool, ok := availableTools[entry.Name]; ok && !tool.versionable() {
+			problems = append(problems, fmt.Sprintf("%s: нельзя установить версию %s, для инструмента не описаны версионированные пакеты или менеджеры версий", entry.Name, entry.Version))
+		}
+	}
+
+	for i := range m.IDE {
+		m.IDE[i].Name = check("ide", m.IDE[i].Name)
+		checkVersion(m.IDE[i])
+	}
+	for i := range m.Tools {
+		m.Tools[i].Name = check("tools", m.Tools[i].Name)
+		checkVersion(m.Tools[i])
+	}
+	for i := range m.Remove {
+		m.Remove[i] = check("remove", m.Remove[i])
+	}
+	if err := validateBackends(m.Via); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return errors.New("манифест содержит ошибки:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// buildPlan сравнивает манифест с текущим состоянием машины
+func buildPlan(ctx context.Context, m *Manifest, osType string) *Plan {
+	plan := &Plan{Stack: EssentialStack}
+	if m.Stack != "" {
+		plan.Stack = StringToStack(m.Stack)
+	}
+
+	classify := func(entry ManifestTool, target *[]PlanStep) {
+		tool, _ := lookupTool(entry.Name)
+		step := PlanStep{Tool: entry.Name, Version: entry.Version}
+		switch {
+		case !tool.present(ctx, osType):
+			step.Action = ActionInstall
+			*target = append(*target, step)
+		case entry.Version != "":
+			// Ограничение версии проверено в validate
+			constraint, _ := parseConstraint(entry.Version)
+			installed, err := tool.installedVersion(ctx, osType)
+			switch {
+			case err != nil:
+				step.Action = ActionUpdate
+				step.Reason = "не удалось определить версию"
+				plan.Update = append(plan.Update, step)
+			case !constraint.Check(installed):
+				// Версия проверена в validate: нужную можно поставить отдельным пакетом или менеджером версий
+				step.Action = ActionInstall
+				step.Reason = fmt.Sprintf("установлена %s", installed)
+				*target = append(*target, step)
+			case m.Update:
+				step.Action = ActionUpdate
+				plan.Update = append(plan.Update, step)
+			default:
+				plan.Unchanged = append(plan.Unchanged, entry.Name)
+			}
+		case m.Update:
+			step.Action = ActionUpdate
+			plan.Update = append(plan.Update, step)
+		default:
+			plan.Unchanged = append(plan.Unchanged, entry.Name)
+		}
+	}
+
+	for _, entry := range m.IDE {
+		classify(entry, &plan.IDE)
+	}
+	for _, entry := range m.Tools {
+		classify(entry, &plan.Install)
+	}
+	for _, name := range m.Remove {
+		if availableTools[name].present(ctx, osType) {
+			plan.Remove = append(plan.Remove, PlanStep{Action: ActionUninstall, Tool: name})
+		} else {
+			plan.Unchanged = append(plan.Unchanged, name)
+		}
+	}
+	return plan
+}
+
+// Empty сообщает, что машина уже соответствует манифесту
+func (p *Plan) Empty() bool {
+	return len(p.Install)+len(p.IDE)+len(p.Update)+len(p.Remove) == 0
+}
+
+// Print выводит план в читаемом виде
+func (p *Plan) Print() {
+	fmt.Printf("План применения манифеста (стек %s):\n", p.Stack)
+	for _, group := range [][]PlanStep{p.IDE, p.Install, p.Update, p.Remove} {
+		for _, step := range group {
+			line := fmt.Sprintf("  %-10s %s", step.Action, step.Tool)
+			if step.Version != "" {
+				line += fmt.Sprintf(" (версия %s)", step.Version)
+			}
+			if step.Reason != "" {
+				line += ": " + step.Reason
+			}
+			fmt.Println(line)
+		}
+	}
+	if len(p.Unchanged) > 0 {
+		fmt.Printf("  Без изменений: %s\n", strings.Join(p.Unchanged, ", "))
+	}
+}
+
+// stepTools возвращает имена инструментов из шагов плана; для установки добавляется
+// ограничение версии, которое validate разрешает только для версионируемых инструментов
+func stepTools(steps []PlanStep) []string {
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		if step.Action == ActionInstall {
+			names = append(names, joinToolSpec(step.Tool, step.Version))
+			continue
+		}
+		names = append(names, step.Tool)
+	}
+	return names
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useManifestTools подменяет каталог инструментами для тестов манифеста
+func useManifestTools(t *testing.T) {
+	t.Helper()
+	java := javaTool
+	java.VersionCommand = "java -version"
+	useTools(t, map[string]Tool{
+		"jq":      jqTool,
+		"Git":     {DetectBinary: "git", Description: "Git", PackageID: "git", VersionCommand: "git --version"},
+		"OpenJDK": java,
+	})
+}
+
+func TestParseManifest(t *testing.T) {
+	useManifestTools(t)
+	tests := []struct {
+		name    string
+		yaml    string
+		want    *Manifest
+		wantErr string
+	}{
+		{
+			name: "строки и объекты",
+			yaml: "stack: python\ntools:\n  - git\n  - {name: OpenJDK, version: \">=17\"}\nremove: [JQ]\nupdate: true\n",
+			want: &Manifest{
+				Stack:  "Python",
+				Tools:  []ManifestTool{{Name: "Git"}, {Name: "OpenJDK", Version: ">=17"}},
+				Remove: []string{"jq"},
+				Update: true,
+			},
+		},
+		{name: "неизвестный инструмент", yaml: "tools: [rustc]\n", wantErr: `tools: неизвестный инструмент "rustc"`},
+		{name: "инструмент в двух разделах", yaml: "tools: [Git]\nremove: [git]\n", wantErr: `remove: инструмент "Git" уже указан в разделе tools`},
+		{name: "неверная версия", yaml: "tools:\n  - {name: OpenJDK, version: \">>17\"}\n", wantErr: "OpenJDK: неверное ограничение версии"},
+		{name: "неизвестный стек", yaml: "stack: Rust\n", wantErr: "Rust"},
+		{name: "неизвестное поле", yaml: "tool: [Git]\n", wantErr: "ошибка разбора манифеста"},
+		{name: "неизвестный менеджер версий", yaml: "via: [rbenv]\n", wantErr: `неизвестный менеджер версий "rbenv"`},
+		{name: "версия неверсионируемого", yaml: "tools:\n  - {name: Git, version: \">=2.50\"}\n", wantErr: "Git: нельзя установить версию >=2.50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseManifest([]byte(tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ошибка %v, ожидалась %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("манифест %+v, ожидался %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	useManifestTools(t)
+	tests := []struct {
+		name string
+		yaml string
+		// want — шаги плана в виде "действие инструмент версия: причина" и строка без изменений
+		want      []string
+		unchanged []string
+	}{
+		{name: "отсутствующий ставится", yaml: "tools: [jq]\n", want: []string{"install jq"}},
+		{name: "установленный не меняется", yaml: "tools: [Git]\n", unchanged: []string{"Git"}},
+		{name: "update обновляет установленные", yaml: "update: true\ntools: [Git]\n", want: []string{"update Git"}},
+		{name: "версия подходит", yaml: "tools:\n  - {name: OpenJDK, version: \">=17\"}\n", unchanged: []string{"OpenJDK"}},
+		{name: "update с подходящей версией обновляет", yaml: "update: true\ntools:\n  - {name: OpenJDK, version: \">=17\"}\n", want: []string{"update OpenJDK >=17"}},
+		{
+			name: "неподходящая версия ставится рядом",
+			yaml: "tools:\n  - {name: OpenJDK, version: \"21\"}\n",
+			want: []string{"install OpenJDK 21: установлена 17.0.2"},
+		},
+		{name: "удаляются только установленные", yaml: "remove: [Git, jq]\n", want: []string{"uninstall Git"}, unchanged: []string{"jq"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeExecutor(t, "apt-get", "git", "java")
+			fake.outputs["sh -c git --version"] = "git version 2.43.0\n"
+			fake.outputs["sh -c java -version"] = "openjdk 17.0.2 2022-01-18\n"
+			manifest, err := parseManifest([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			plan := buildPlan(context.Background(), manifest, "linux")
+			var got []string
+			for _, group := range [][]PlanStep{plan.IDE, plan.Install, plan.Update, plan.Remove} {
+				for _, step := range group {
+					line := strings.TrimSpace(fmt.Sprintf("%s %s %s", actionName(step.Action), step.Tool, step.Version))
+					if step.Reason != "" {
+						line += ": " + step.Reason
+					}
+					got = append(got, line)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("шаги %q, ожидались %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(plan.Unchanged, tt.unchanged) {
+				t.Errorf("без изменений %q, ожидались %q", plan.Unchanged, tt.unchanged)
+			}
+		})
+	}
+}
+
+func TestStepTools(t *testing.T) {
+	useManifestTools(t)
+	steps := []PlanStep{
+		{Action: ActionInstall, Tool: "OpenJDK", Version: "21"},
+		{Action: ActionInstall, Tool: "jq"},
+		{Action: ActionUpdate, Tool: "OpenJDK", Version: ">=17"},
+	}
+	if got, want := stepTools(steps), []string{"OpenJDK@21", "jq", "OpenJDK"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("инструменты %q, ожидались %q", got, want)
+	}
+}
+
+// actionName возвращает короткое имя действия для сравнения планов
+func actionName(action Action) string {
+	switch action {
+	case ActionInstall:
+		return "install"
+	case ActionUpdate:
+		return "update"
+	case ActionUninstall:
+		return "uninstall"
+	}
+	return string(action)
+}
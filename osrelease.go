@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// osReleasePath — путь к описанию дистрибутива Linux
+var osReleasePath = "/etc/os-release"
+
+// distroPackageManagers сопоставляет ID дистрибутива из os-release
+// с пакетными менеджерами в порядке предпочтения
+var distroPackageManagers = map[string][]string{
+	"debian":      {"apt"},
+	"ubuntu":      {"apt"},
+	"linuxmint":   {"apt"},
+	"pop":         {"apt"},
+	"raspbian":    {"apt"},
+	"fedora":      {"dnf", "yum"},
+	"rhel":        {"dnf", "yum"},
+	"centos":      {"dnf", "yum"},
+	"rocky":       {"dnf", "yum"},
+	"almalinux":   {"dnf", "yum"},
+	"amzn":        {"dnf", "yum"},
+	"arch":        {"pacman"},
+	"manjaro":     {"pacman"},
+	"endeavouros": {"pacman"},
+	"opensuse":    {"zypper"},
+	"suse":        {"zypper"},
+	"sles":        {"zypper"},
+	"alpine":      {"apk"},
+	"void":        {"xbps"},
+	"gentoo":      {"emerge"},
+}
+
+// readOSRelease разбирает файл os-release в карту ключ-значение
+func readOSRelease(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	release := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		release[key] = strings.Trim(value, `"'`)
+	}
+	return release, scanner.Err()
+}
+
+// distroIDs возвращает ID дистрибутива и родственные ID из ID_LIKE
+func distroIDs(release map[string]string) []string {
+	var ids []string
+	if id := release["ID"]; id != "" {
+		ids = append(ids, id)
+	}
+	return append(ids, strings.Fields(release["ID_LIKE"])...)
+}
+
+// distroPackageManagerNames возвращает менеджеры, подходящие дистрибутиву.
+// Варианты openSUSE (opensuse-leap, opensuse-tumbleweed) сводятся к общему ID.
+func distroPackageManagerNames(release map[string]string) []string {
+	var names []string
+	for _, id := range distroIDs(release) {
+		if strings.HasPrefix(id, "opensuse") {
+			id = "opensuse"
+		}
+		for _, name := range distroPackageManagers[id] {
+			if !contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
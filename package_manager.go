@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// PackageManager описывает системный пакетный менеджер.
+// Чтобы добавить новый менеджер, достаточно реализовать интерфейс и зарегистрировать его в packageManagers.
+type PackageManager interface {
+	// Name возвращает имя менеджера, которое используется как ключ в каталоге
+	Name() string
+	// OS возвращает операционную систему, на которой работает менеджер
+	OS() string
+	// Detect сообщает, доступен ли менеджер в системе
+	Detect(osType string) bool
+	// Install устанавливает пакет
+	Install(ctx context.Context, pkg string) error
+	// Upgrade обновляет пакет
+	Upgrade(ctx context.Context, pkg string) error
+	// Remove удаляет пакет
+	Remove(ctx context.Context, pkg string) error
+	// IsInstalled проверяет, установлен ли пакет через этот менеджер
+	IsInstalled(ctx context.Context, pkg string) bool
+	// InstalledVersion возвращает установленную версию пакета
+	InstalledVersion(ctx context.Context, pkg string) (string, error)
+	// Available проверяет, есть ли пакет в репозиториях менеджера
+	Available(ctx context.Context, pkg string) bool
+	// Refresh обновляет индекс пакетов
+	Refresh(ctx context.Context) error
+	// Owns сообщает, установлен ли файл path пакетом этого менеджера
+	Owns(ctx context.Context, path string) bool
+}
+
+// cliPackageManager — пакетный менеджер, управляемый через командную строку
+type cliPackageManager struct {
+	name string
+	// os — операционная система, на которой работает менеджер
+	os string
+	// binary — исполняемый файл, по которому определяется наличие менеджера
+	binary string
+	// sudo — требуются ли права root для изменения пакетов
+	sudo bool
+
+	// install, upgrade, remove и refresh — команды действий без имени пакета
+	install string
+	upgrade string
+	remove  string
+	refresh string
+
+	// query — команда проверки установленного пакета, %s заменяется именем пакета
+	query string
+	// installed — регулярное выражение для вывода query, %s заменяется идентификатором пакета;
+	// пусто — достаточно успешного завершения
+	installed string
+	// version — команда получения версии пакета, %s заменяется именем пакета
+	version string
+	// parseVersion извлекает версию из вывода команды version
+	parseVersion func(output string) string
+	// available — команда поиска пакета в репозиториях, %s заменяется именем пакета
+	available string
+	// owner — команда, которая завершается успешно, если файл принадлежит пакету; %s — путь
+	owner string
+	// prefixes — каталоги, все файлы в которых установлены менеджером (Homebrew, Chocolatey)
+	prefixes []string
+}
+
+// packageManagers содержит поддерживаемые пакетные менеджеры в порядке предпочтения
+var packageManagers = []PackageManager{
+	&cliPackageManager{
+		name:    "apt",
+		os:      "linux",
+		binary:  "apt-get",
+		sudo:    true,
+		install: "apt-get install -y",
+		upgrade: "apt-get install --only-upgrade -y",
+		remove:  "apt-get remove -y",
+		refresh: "apt-get update",
+		// dpkg -s успешен и для удаленного пакета с оставшимися настройками (состояние deinstall ok config-files)
+		query:        "dpkg-query -W -f='${Status}' %s",
+		installed:    "install ok installed",
+		version:      "dpkg-query -W -f='${Version}' %s",
+		parseVersion: strings.TrimSpace,
+		available:    "apt-cache show %s",
+		owner:        "dpkg -S %s",
+	},
+	&cliPackageManager{
+		name:         "dnf",
+		os:           "linux",
+		binary:       "dnf",
+		sudo:         true,
+		install:      "dnf install -y",
+		upgrade:      "dnf upgrade -y",
+		remove:       "dnf remove -y",
+		refresh:      "dnf makecache",
+		query:        "rpm -q %s",
+		version:      "rpm -q --qf '%%{VERSION}' %s",
+		parseVersion: strings.TrimSpace,
+		available:    "dnf info %s",
+		owner:        "rpm -qf %s",
+	},
+	&cliPackageManager{
+		name:         "yum",
+		os:           "linux",
+		binary:       "yum",
+		sudo:         true,
+		install:      "yum install -y",
+		upgrade:      "yum update -y",
+		remove:       "yum remove -y",
+		refresh:      "yum makecache",
+		query:        "rpm -q %s",
+		version:      "rpm -q --qf '%%{VERSION}' %s",
+		parseVersion: strings.TrimSpace,
+		available:    "yum info %s",
+		owner:        "rpm -qf %s",
+	},
+	&cliPackageManager{
+		name:    "pacman",
+		os:      "linux",
+		binary:  "pacman",
+		sudo:    true,
+		install: "pacman -S --noconfirm --needed",
+		upgrade: "pacman -Syu --noconfirm",
+		remove:  "pacman -R --noconfirm",
+		// Индекс не обновляется: pacman -Sy без -u перед установкой — частичное обновление,
+		// которое Arch не поддерживает; свежий индекс дает только полное обновление системы
+		query:        "pacman -Q %s",
+		version:      "pacman -Q %s",
+		parseVersion: secondField,
+		available:    "pacman -Si %s",
+		owner:        "pacman -Qo %s",
+	},
+	&cliPackageManager{
+		name:         "zypper",
+		os:           "linux",
+		binary:       "zypper",
+		sudo:         true,
+		install:      "zypper --non-interactive install",
+		upgrade:      "zypper --non-interactive update",
+		remove:       "zypper --non-interactive remove",
+		refresh:      "zypper --non-interactive refresh",
+		query:        "rpm -q %s",
+		version:      "rpm -q --qf '%%{VERSION}' %s",
+		parseVersion: strings.TrimSpace,
+		available:    "zypper --non-interactive search -x %s",
+		owner:        "rpm -qf %s",
+	},
+	&cliPackageManager{
+		name:         "apk",
+		os:           "linux",
+		binary:       "apk",
+		sudo:         true,
+		install:      "apk add",
+		upgrade:      "apk add --upgrade",
+		remove:       "apk del",
+		refresh:      "apk update",
+		query:        "apk info -e %s",
+		version:      "apk list --installed %s",
+		parseVersion: parseApkVersion,
+		available:    "apk search -e %s",
+		owner:        "apk info --who-owns %s",
+	},
+	&cliPackageManager{
+		name:         "xbps",
+		os:           "linux",
+		binary:       "xbps-install",
+		sudo:         true,
+		install:      "xbps-install -y",
+		upgrade:      "xbps-install -yu",
+		remove:       "xbps-remove -y",
+		refresh:      "xbps-install -S",
+		query:        "xbps-query %s",
+		version:      "xbps-query -p pkgver %s",
+		parseVersion: parseXbpsVersion,
+		available:    "xbps-query -R %s",
+		owner:        "xbps-query -o %s",
+	},
+	&cliPackageManager{
+		name:    "emerge",
+		os:      "linux",
+		binary:  "emerge",
+		sudo:    true,
+		install: "emerge --ask=n --noreplace",
+		upgrade: "emerge --ask=n --update",
+		remove:  "emerge --ask=n --depclean",
+		// Дерево Portage не синхронизируется: emerge --sync долгий, а зеркала ограничивают частоту синхронизации
+		// В Gentoo пакеты указываются с категорией (dev-vcs/git), база установленных пакетов лежит в /var/db/pkg
+		query:        "ls -d /var/db/pkg/%s-[0-9]*",
+		version:      "ls -d /var/db/pkg/%s-[0-9]*",
+		parseVersion: parseGentooVersion,
+		available:    "emerge -p --nodeps %s",
+		owner:        "qfile -q %s",
+	},
+	&cliPackageManager{
+		name:         "brew",
+		os:           "darwin",
+		binary:       "brew",
+		install:      "brew install",
+		upgrade:      "brew upgrade",
+		remove:       "brew uninstall",
+		refresh:      "brew update",
+		query:        "brew list %s",
+		version:      "brew list --versions %s",
+		parseVersion: secondField,
+		available:    "brew info %s",
+		prefixes:     []string{"/opt/homebrew/", "/usr/local/Cellar/", "/usr/local/Caskroom/", "/home/linuxbrew/.linuxbrew/"},
+	},
+	&cliPackageManager{
+		name:    "choco",
+		os:      "windows",
+		binary:  "choco",
+		install: "choco install -y",
+		upgrade: "choco upgrade -y",
+		remove:  "choco uninstall -y",
+		// choco list завершается успешно и без пакета ("0 packages installed"), поэтому ищется строка "id|версия"
+		query:     "choco list --local-only --exact --limit-output %s",
+		installed: `(?im)^%s\|`,
+		version:   "choco list --local-only --exact --limit-output %s",
+		parseVersion: func(output string) string {
+			if _, version, ok := strings.Cut(strings.TrimSpace(output), "|"); ok {
+				return version
+			}
+			return ""
+		},
+		available: "choco search --exact --limit-output %s",
+		prefixes:  []string{`C:\ProgramData\chocolatey\`},
+	},
+}
+
+// packageDatabases сопоставляет менеджеры, работающие с общей базой пакетов
+var packageDatabases = map[string]string{
+	"dnf":    "rpm",
+	"yum":    "rpm",
+	"zypper": "rpm",
+}
+
+// packageLocks сериализует вызовы менеджеров: apt, dnf и brew берут эксклюзивную
+// блокировку базы пакетов, и параллельные вызовы завершаются ошибкой
+var packageLocks = struct {
+	sync.Mutex
+	locks map[string]*sync.Mutex
+}{locks: make(map[string]*sync.Mutex)}
+
+// lockPackages захватывает блокировку базы пакетов менеджера name и возвращает функцию ее освобождения.
+// Работа, не затрагивающая пакеты (git clone, загрузки), выполняется без блокировки и идет параллельно.
+func lockPackages(name string) func() {
+	key := name
+	if db, ok := packageDatabases[name]; ok {
+		key = db
+	}
+
+	packageLocks.Lock()
+	lock, ok := packageLocks.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		packageLocks.locks[key] = lock
+	}
+	packageLocks.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// packageRefreshes запоминает, для каких менеджеров индекс пакетов уже обновлен в этом запуске
+var packageRefreshes = struct {
+	sync.Mutex
+	once map[string]*sync.Once
+}{once: make(map[string]*sync.Once)}
+
+// refreshOnce обновляет индекс пакетов менеджера перед первой установкой в этом запуске:
+// на свежей системе списки пакетов apt пусты, и ни установка, ни поиск пакета не работают.
+// Ошибка обновления не мешает установке из уже загруженного индекса, поэтому только выводится.
+func refreshOnce(ctx context.Context, pm PackageManager) {
+	packageRefreshes.Lock()
+	once, ok := packageRefreshes.once[pm.Name()]
+	if !ok {
+		once = &sync.Once{}
+		packageRefreshes.once[pm.Name()] = once
+	}
+	packageRefreshes.Unlock()
+
+	once.Do(func() {
+		// Обновление индекса общее для всех инструментов и в запись об установке не попадает
+		if err := pm.Refresh(withoutRecorder(ctx)); err != nil {
+			log.Printf("Не удалось обновить индекс пакетов %s: %v\n", pm.Name(), err)
+		}
+	})
+}
+
+// isRoot сообщает, запущена ли программа от имени root
+var isRoot = func() bool {
+	return os.Geteuid() == 0
+}
+
+func (m *cliPackageManager) Name() string {
+	return m.name
+}
+
+func (m *cliPackageManager) OS() string {
+	return m.os
+}
+
+func (m *cliPackageManager) Detect(osType string) bool {
+	if osType != m.os {
+		return false
+	}
+	_, err := executor.LookPath(m.binary)
+	return err == nil
+}
+
+func (m *cliPackageManager) Install(ctx context.Context, pkg string) error {
+	return m.run(ctx, m.install, pkg)
+}
+
+func (m *cliPackageManager) Upgrade(ctx context.Context, pkg string) error {
+	return m.run(ctx, m.upgrade, pkg)
+}
+
+func (m *cliPackageManager) Remove(ctx context.Context, pkg string) error {
+	return m.run(ctx, m.remove, pkg)
+}
+
+func (m *cliPackageManager) Refresh(ctx context.Context) error {
+	if m.refresh == "" {
+		return nil
+	}
+	return m.run(ctx, m.refresh, "")
+}
+
+func (m *cliPackageManager) IsInstalled(ctx context.Context, pkg string) bool {
+	output, err := commandOutput(ctx, fmt.Sprintf(m.query, pkg), m.os)
+	if err != nil || m.installed == "" {
+		return err == nil
+	}
+	pattern := m.installed
+	if strings.Contains(pattern, "%s") {
+		// Версионированный пакет choco записывается с аргументами (temurin --version 21.0.2), идентификатор — первое слово
+		id, _, _ := strings.Cut(pkg, " ")
+		pattern = fmt.Sprintf(pattern, regexp.QuoteMeta(id))
+	}
+	matched, err := regexp.MatchString(pattern, output)
+	return err == nil && matched
+}
+
+func (m *cliPackageManager) Owns(ctx context.Context, path string) bool {
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(strings.ToLower(path), strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	if m.owner == "" {
+		return false
+	}
+	_, err := commandOutput(ctx, fmt.Sprintf(m.owner, shellQuote(path)), m.os)
+	return err == nil
+}
+
+func (m *cliPackageManager) Available(ctx context.Context, pkg string) bool {
+	if m.available == "" {
+		return true
+	}
+	output, err := commandOutput(ctx, fmt.Sprintf(m.available, pkg), m.os)
+	return err == nil && strings.TrimSpace(output) != ""
+}
+
+func (m *cliPackageManager) InstalledVersion(ctx context.Context, pkg string) (string, error) {
+	output, err := commandOutput(ctx, fmt.Sprintf(m.version, pkg), m.os)
+	if err != nil {
+		return "", fmt.Errorf("пакет %s не установлен через %s", pkg, m.name)
+	}
+	version := m.parseVersion(output)
+	if version == "" {
+		return "", fmt.Errorf("не удалось определить версию пакета %s", pkg)
+	}
+	return version, nil
+}
+
+// commandLine формирует командную строку менеджера с учетом sudo
+func (m *cliPackageManager) commandLine(action, pkg string) string {
+	line := strings.TrimSpace(action + " " + pkg)
+	if m.sudo && !isRoot() {
+		line = "sudo " + line
+	}
+	return line
+}
+
+// run выполняет команду менеджера, дожидаясь завершения других вызовов того же менеджера
+func (m *cliPackageManager) run(ctx context.Context, action, pkg string) error {
+	defer lockPackages(m.name)()
+	return runCommand(ctx, m.commandLine(action, pkg), m.os)
+}
+
+// secondField возвращает второе слово вывода вида "пакет версия"
+func secondField(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// parseApkVersion извлекает версию из строки вида "py3-pip-23.1-r0 x86_64 {py3-pip} (MIT) [installed]"
+func parseApkVersion(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return ""
+	}
+	pkgver := fields[0]
+	if i := strings.LastIndex(pkgver, "-r"); i > 0 {
+		pkgver = pkgver[:i]
+	}
+	if i := strings.LastIndex(pkgver, "-"); i >= 0 {
+		return pkgver[i+1:]
+	}
+	return ""
+}
+
+// parseXbpsVersion извлекает версию из строки вида "jq-1.7.1_1"
+func parseXbpsVersion(output string) string {
+	pkgver := strings.TrimSpace(output)
+	if i := strings.LastIndex(pkgver, "_"); i > 0 {
+		pkgver = pkgver[:i]
+	}
+	if i := strings.LastIndex(pkgver, "-"); i >= 0 {
+		return pkgver[i+1:]
+	}
+	return ""
+}
+
+// parseGentooVersion извлекает версию из пути вида "/var/db/pkg/app-misc/jq-1.7.1-r1"
+func parseGentooVersion(output string) string {
+	lines := strings.Fields(output)
+	if len(lines) == 0 {
+		return ""
+	}
+	name := lines[len(lines)-1]
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	// Версия начинается после первого дефиса, за которым следует цифра
+	for i := 0; i+1 < len(name); i++ {
+		if name[i] == '-' && name[i+1] >= '0' && name[i+1] <= '9' {
+			version := name[i+1:]
+			if j := strings.LastIndex(version, "-r"); j > 0 {
+				version = version[:j]
+			}
+			return version
+		}
+	}
+	return ""
+}
+
+// commandOutput выполняет команду через оболочку и возвращает ее вывод
+func commandOutput(ctx context.Context, command, osType string) (string, error) {
+	return executor.Output(ctx, shellFor(osType), shellFlag(osType), command)
+}
+
+// packageManagerNames возвращает имена всех зарегистрированных менеджеров
+func packageManagerNames() []string {
+	names := make([]string, 0, len(packageManagers))
+	for _, pm := range packageManagers {
+		names = append(names, pm.Name())
+	}
+	return names
+}
+
+// packageManagerByName возвращает зарегистрированный менеджер по имени
+func packageManagerByName(name string) PackageManager {
+	for _, pm := range packageManagers {
+		if pm.Name() == name {
+			return pm
+		}
+	}
+	return nil
+}
+
+// detectPackageManager выбирает пакетный менеджер для текущей ОС.
+// В Linux сначала проверяются менеджеры, подходящие дистрибутиву по /etc/os-release,
+// и только затем все остальные в порядке регистрации.
+func detectPackageManager(osType string) (PackageManager, error) {
+	if osType == "linux" {
+		if release, err := readOSRelease(osReleasePath); err == nil {
+			for _, name := range distroPackageManagerNames(release) {
+				if pm := packageManagerByName(name); pm != nil && pm.Detect(osType) {
+					return pm, nil
+				}
+			}
+		}
+	}
+
+	for _, pm := range packageManagers {
+		if pm.Detect(osType) {
+			return pm, nil
+		}
+	}
+
+	switch osType {
+	case "windows":
+		return nil, fmt.Errorf("Chocolatey не установлен. Установите его выполнив следующую команду в PowerShell с правами администратора:\nSet-ExecutionPolicy Bypass -Scope Process -Force; [System.Net.ServicePointManager]::SecurityProtocol = [System.Net.ServicePointManager]::SecurityProtocol -bor 3072; iex ((New-Object System.Net.WebClient).DownloadString('https://community.chocolatey.org/install.ps1'))")
+	case "darwin":
+		return nil, fmt.Errorf("Homebrew не установлен. Установите его с https://brew.sh/")
+	case "linux":
+		return nil, fmt.Errorf("не найден поддерживаемый пакетный менеджер")
+	}
+	return nil, fmt.Errorf("неподдерживаемая операционная система: %s", osType)
+}
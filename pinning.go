@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// versionTemplateData — данные для шаблонов версионированных пакетов,
+// например "openjdk-{{.Major}}-jdk" или "node@{{.Major}}"
+type versionTemplateData struct {
+	// Version — выбранная версия в том виде, как она записана ("21", "1.22", "20.11.1")
+	Version string
+	Major   int
+	Minor   int
+	Patch   int
+}
+
+// splitToolSpec разделяет запись вида "node@20" на имя инструмента и ограничение версии
+func splitToolSpec(spec string) (name, pin string) {
+	name, pin, _ = strings.Cut(spec, "@")
+	return name, pin
+}
+
+// joinToolSpec собирает запись "имя@версия"; без версии возвращает имя
+func joinToolSpec(name, pin string) string {
+	if pin == "" {
+		return name
+	}
+	return name + "@" + pin
+}
+
+// lookupTool возвращает инструмент каталога по записи "имя" или "имя@версия".
+// Ограничение версии сохраняется в поле Pin копии инструмента, выбранный менеджер версий — в Via.
+func lookupTool(spec string) (Tool, bool) {
+	name, pin := splitToolSpec(spec)
+	tool, ok := availableTools[name]
+	if !ok {
+		return Tool{}, false
+	}
+	tool.Name = name
+	tool.Pin = pin
+	tool.Via = chooseBackend(tool)
+	return tool, true
+}
+
+// versionable сообщает, можно ли установить конкретную версию инструмента:
+// через версионированные пакеты или менеджер версий
+func (t Tool) versionable() bool {
+	return len(t.VersionedPackages) > 0 || len(t.Backends) > 0
+}
+
+// pickVersion выбирает версию, удовлетворяющую ограничению.
+// Точная версия (20.11.1 или =20.11.1) используется как есть, иначе выбирается
+// наибольшая подходящая версия из списка versions каталога.
+func pickVersion(pin string, versions []string) (string, error) {
+	constraint, err := parseConstraint(pin)
+	if err != nil {
+		return "", err
+	}
+	if exact, ok := constraint.exact(); ok {
+		return exact, nil
+	}
+
+	candidates := append([]string(nil), versions...)
+	sort.Slice(candidates, func(i, j int) bool {
+		vi, _ := parseVersion(candidates[i])
+		vj, _ := parseVersion(candidates[j])
+		return vi.Compare(vj) > 0
+	})
+	for _, candidate := range candidates {
+		if v, err := parseVersion(candidate); err == nil && constraint.Check(v) {
+			return candidate, nil
+		}
+	}
+
+	if len(versions) == 0 {
+		return "", fmt.Errorf("для инструмента не указаны известные версии, укажите точную версию вида 1.2.3")
+	}
+	return "", fmt.Errorf("ни одна из известных версий (%s) не удовлетворяет ограничению %q", strings.Join(versions, ", "), pin)
+}
+
+// exact возвращает версию, если ограничение задает ровно одну версию major.minor.patch
+func (c Constraint) exact() (string, bool) {
+	if len(c.comparators) != 1 {
+		return "", false
+	}
+	cmp := c.comparators[0]
+	if cmp.parts != 3 || (cmp.op != "" && cmp.op != "=" && cmp.op != "==") {
+		return "", false
+	}
+	return cmp.version.String(), true
+}
+
+// pinnedPackage возвращает имя пакета для версии t.Pin и менеджера pm
+func (t Tool) pinnedPackage(osType, pm string) (string, error) {
+	tmpl := t.VersionedPackages[pm]
+	if tmpl == "" {
+		tmpl = t.VersionedPackages[osType]
+	}
+	if tmpl == "" {
+		return "", fmt.Errorf("выбор версии не поддерживается пакетным менеджером %s", pm)
+	}
+
+	chosen, err := pickVersion(t.Pin, t.Versions)
+	if err != nil {
+		return "", err
+	}
+	v, err := parseVersion(chosen)
+	if err != nil {
+		return "", err
+	}
+	return renderTemplate(tmpl, versionTemplateData{Version: chosen, Major: v.Major, Minor: v.Minor, Patch: v.Patch})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var javaTool = Tool{
+	DetectBinary: "java",
+	Description:  "OpenJDK",
+	Packages:     map[string]string{"apt": "openjdk-11-jdk", "brew": "openjdk"},
+	Versions:     []string{"11", "17", "21"},
+	VersionedPackages: map[string]string{
+		"apt":   "openjdk-{{.Major}}-jdk",
+		"brew":  "openjdk@{{.Major}}",
+		"choco": "temurin --version {{.Version}}",
+	},
+}
+
+func TestPickVersion(t *testing.T) {
+	versions := []string{"1.21.13", "1.22.8", "1.23.2"}
+	tests := []struct {
+		pin  string
+		want string
+	}{
+		{"1.22", "1.22.8"},
+		{">=1.22,<1.24", "1.23.2"},
+		{"<1.23", "1.22.8"},
+		{"~1.21", "1.21.13"},
+		{"1.22.3", "1.22.3"},
+		{"=1.20.1", "1.20.1"},
+	}
+	for _, tt := range tests {
+		got, err := pickVersion(tt.pin, versions)
+		if err != nil {
+			t.Errorf("%s: %v", tt.pin, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: выбрана %s, ожидалась %s", tt.pin, got, tt.want)
+		}
+	}
+
+	if _, err := pickVersion(">=1.30", versions); err == nil || !strings.Contains(err.Error(), "1.21.13, 1.22.8, 1.23.2") {
+		t.Errorf("ожидалась ошибка со списком версий, получено %v", err)
+	}
+}
+
+func TestExecuteCommandPinned(t *testing.T) {
+	tests := []struct {
+		pm     string
+		osType string
+		binary string
+		pin    string
+		search string
+		want   string
+	}{
+		{"apt", "linux", "apt-get", "21", "sh -c apt-cache show openjdk-21-jdk", "sudo apt-get install -y openjdk-21-jdk"},
+		{"brew", "darwin", "brew", "^17", "sh -c brew info openjdk@17", "brew install openjdk@17"},
+		{"choco", "windows", "choco", "21.0.2", "powershell -Command choco search --exact --limit-output temurin --version 21.0.2", "choco install -y temurin --version 21.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pm, func(t *testing.T) {
+			useTestPlatform(t, Platform{OS: tt.osType, Arch: "amd64"})
+			fake := useFakeExecutor(t, tt.binary)
+			fake.outputs[tt.search] = "found\n"
+
+			tool := javaTool
+			tool.Pin = tt.pin
+			if err := executeCommand(context.Background(), tt.osType, "install", tool); err != nil {
+				t.Fatal(err)
+			}
+			if got := fake.commands(); !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Errorf("команды %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteCommandPinnedUnavailable(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+
+	tool := javaTool
+	tool.Pin = "17"
+	err := executeCommand(context.Background(), "linux", "install", tool)
+	if err == nil || !strings.Contains(err.Error(), "openjdk-17-jdk для версии 17 недоступен") {
+		t.Fatalf("ожидалась ошибка недоступной версии, получено %v", err)
+	}
+	if len(fake.runs) != 0 {
+		t.Errorf("выполнены команды %q", fake.commands())
+	}
+}
+
+func TestExecuteCommandPinnedUnsupportedManager(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	useFakeExecutor(t, "pacman")
+
+	tool := javaTool
+	tool.Pin = "21"
+	err := executeCommand(context.Background(), "linux", "install", tool)
+	if err == nil || !strings.Contains(err.Error(), "не поддерживается пакетным менеджером pacman") {
+		t.Fatalf("ожидалась ошибка неподдерживаемого менеджера, получено %v", err)
+	}
+}
+
+func TestResolveToolNamesWithVersion(t *testing.T) {
+	useTools(t, map[string]Tool{"OpenJDK": javaTool, "jq": jqTool})
+
+	got, err := resolveToolNames([]string{"java@21", "jq"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"OpenJDK@21", "jq"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("получено %q, ожидалось %q", got, want)
+	}
+	if _, err := resolveToolNames([]string{"jq@1.7"}); err == nil {
+		t.Error("ожидалась ошибка для инструмента без версионированных пакетов")
+	}
+	if _, err := resolveToolNames([]string{"java@latest"}); err == nil {
+		t.Error("ожидалась ошибка неверного ограничения")
+	}
+}
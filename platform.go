@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Platform описывает систему, на которой запущена программа
+type Platform struct {
+	// OS — windows, darwin или linux
+	OS string
+	// Distro — ID дистрибутива из /etc/os-release (ubuntu, fedora, arch...)
+	Distro string
+	// DistroLike — родственные дистрибутивы из ID_LIKE
+	DistroLike []string
+	// Version — VERSION_ID из /etc/os-release
+	Version string
+	// Codename — кодовое имя версии (jammy, bookworm)
+	Codename string
+	// Arch — архитектура в терминах Go (amd64, arm64, arm, 386)
+	Arch string
+	// Kernel — версия ядра из uname -r
+	Kernel string
+	// WSL — запущено ли в Windows Subsystem for Linux
+	WSL bool
+	// Container — запущено ли в контейнере
+	Container bool
+}
+
+var (
+	platformOnce sync.Once
+	platform     Platform
+)
+
+// currentPlatform возвращает сведения о текущей системе; определение выполняется один раз
+func currentPlatform() Platform {
+	platformOnce.Do(func() {
+		platform = detectPlatform()
+	})
+	return platform
+}
+
+// detectPlatform собирает сведения о системе из runtime, /etc/os-release и uname
+func detectPlatform() Platform {
+	p := Platform{
+		OS:   detectOS(),
+		Arch: runtime.GOARCH,
+	}
+
+	if p.OS != "windows" {
+		if output, err := executor.Output(context.Background(), "uname", "-r"); err == nil {
+			p.Kernel = strings.TrimSpace(output)
+		}
+	}
+
+	if p.OS == "linux" {
+		if release, err := readOSRelease(osReleasePath); err == nil {
+			p.applyOSRelease(release)
+		}
+		p.WSL = detectWSL(p.Kernel)
+		p.Container = detectContainer()
+	}
+	return p
+}
+
+// applyOSRelease заполняет сведения о дистрибутиве из полей /etc/os-release
+func (p *Platform) applyOSRelease(release map[string]string) {
+	p.Distro = release["ID"]
+	p.DistroLike = strings.Fields(release["ID_LIKE"])
+	p.Version = release["VERSION_ID"]
+	// Производные дистрибутивы (Linux Mint, Pop!_OS, LMDE) указывают кодовое имя базы
+	// отдельно: сторонние репозитории публикуются только для него
+	for _, key := range []string{"UBUNTU_CODENAME", "DEBIAN_CODENAME", "VERSION_CODENAME"} {
+		if p.Codename = release[key]; p.Codename != "" {
+			break
+		}
+	}
+}
+
+// detectWSL определяет запуск в WSL по версии ядра и переменным окружения
+func detectWSL(kernel string) bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	kernel = strings.ToLower(kernel)
+	return strings.Contains(kernel, "microsoft") || strings.Contains(kernel, "wsl")
+}
+
+// detectContainer определяет запуск в Docker, Podman, LXC или Kubernetes
+func detectContainer() bool {
+	if os.Getenv("container") != "" {
+		return true
+	}
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+	cgroup, err := os.ReadFile("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	for _, runtimeName := range []string{"docker", "kubepods", "containerd", "lxc", "libpod"} {
+		if strings.Contains(string(cgroup), runtimeName) {
+			return true
+		}
+	}
+	return false
+}
+
+// repoDistros — дистрибутивы, для которых поставщики публикуют собственные репозитории
+var repoDistros = map[string]bool{
+	"ubuntu":   true,
+	"debian":   true,
+	"raspbian": true,
+	"fedora":   true,
+	"centos":   true,
+	"rhel":     true,
+	"sles":     true,
+}
+
+// RepoDistro возвращает дистрибутив для адресов сторонних репозиториев. Для производного
+// дистрибутива (linuxmint, pop) берется первый подходящий из ID_LIKE, иначе ID.
+func (p Platform) RepoDistro() string {
+	if repoDistros[p.Distro] {
+		return p.Distro
+	}
+	for _, like := range p.DistroLike {
+		if repoDistros[like] {
+			return like
+		}
+	}
+	return p.Distro
+}
+
+// DebArch возвращает архитектуру в терминах Debian (amd64, arm64, armhf, i386)
+func (p Platform) DebArch() string {
+	switch p.Arch {
+	case "arm":
+		return "armhf"
+	case "386":
+		return "i386"
+	default:
+		return p.Arch
+	}
+}
+
+// RPMArch возвращает архитектуру в терминах RPM (x86_64, aarch64)
+func (p Platform) RPMArch() string {
+	switch p.Arch {
+	case "amd64":
+		return "x86_64"
+	case "arm64":
+		return "aarch64"
+	case "arm":
+		return "armv7hl"
+	case "386":
+		return "i686"
+	default:
+		return p.Arch
+	}
+}
+
+// expand подставляет сведения о платформе в шаблон вида "{{.RepoDistro}} {{.Codename}}"
+func (p Platform) expand(text string) (string, error) {
+	return renderTemplate(text, p)
+}
+
+// renderTemplate подставляет данные в шаблон text/template
+func renderTemplate(text string, data interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("ошибка разбора шаблона %q: %v", text, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("ошибка подстановки в шаблон %q: %v", text, err)
+	}
+	return buf.String(), nil
+}
+
+// checkTemplate проверяет шаблон, подставляя пустые данные того же типа
+func checkTemplate(text string, data interface{}) error {
+	_, err := renderTemplate(text, data)
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var platformCmd = &cobra.Command{
+	Use:   "platform",
+	Short: "Показать сведения об операционной системе, дистрибутиве и архитектуре",
+	Example: "  dev-installer platform\n" +
+		"  dev-installer platform --output json",
+	RunE: platformRun,
+}
+
+// PlatformInfo — сведения о системе для вывода командой platform
+type PlatformInfo struct {
+	OS         string   `json:"os"`
+	Distro     string   `json:"distro,omitempty"`
+	DistroLike []string `json:"distro_like,omitempty"`
+	// RepoDistro — дистрибутив, для которого подключаются репозитории поставщиков
+	RepoDistro string `json:"repo_distro,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Codename   string `json:"codename,omitempty"`
+	Arch       string `json:"arch"`
+	Kernel     string `json:"kernel,omitempty"`
+	WSL        bool   `json:"wsl"`
+	Container  bool   `json:"container"`
+	// PackageManager — выбранный пакетный менеджер; пусто, если не найден
+	PackageManager string `json:"package_manager,omitempty"`
+}
+
+func platformRun(cmd *cobra.Command, args []string) error {
+	p := currentPlatform()
+	info := PlatformInfo{
+		OS:        p.OS,
+		Arch:      p.Arch,
+		Kernel:    p.Kernel,
+		WSL:       p.WSL,
+		Container: p.Container,
+	}
+	if p.OS == "linux" {
+		info.Distro, info.DistroLike, info.RepoDistro = p.Distro, p.DistroLike, p.RepoDistro()
+		info.Version, info.Codename = p.Version, p.Codename
+	}
+	if pm, err := detectPackageManager(p.OS); err == nil {
+		info.PackageManager = pm.Name()
+	}
+
+	if outputFormat == "json" {
+		enc := json.NewEncoder(resultOutput)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	w := tabwriter.NewWriter(resultOutput, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ОС:\t%s\n", info.OS)
+	if info.OS == "linux" {
+		fmt.Fprintf(w, "Дистрибутив:\t%s\n", info.Distro)
+		fmt.Fprintf(w, "Родственные:\t%s\n", strings.Join(info.DistroLike, ", "))
+		fmt.Fprintf(w, "Репозитории для:\t%s\n", info.RepoDistro)
+		fmt.Fprintf(w, "Версия:\t%s\n", info.Version)
+		fmt.Fprintf(w, "Кодовое имя:\t%s\n", info.Codename)
+	}
+	fmt.Fprintf(w, "Архитектура:\t%s\n", info.Arch)
+	if info.Kernel != "" {
+		fmt.Fprintf(w, "Ядро:\t%s\n", info.Kernel)
+	}
+	if info.OS == "linux" {
+		fmt.Fprintf(w, "WSL:\t%s\n", yesNo(info.WSL))
+		fmt.Fprintf(w, "Контейнер:\t%s\n", yesNo(info.Container))
+	}
+	pmName := info.PackageManager
+	if pmName == "" {
+		pmName = "не найден"
+	}
+	fmt.Fprintf(w, "Пакетный менеджер:\t%s\n", pmName)
+	return w.Flush()
+}
+
+// yesNo возвращает "да" или "нет"
+func yesNo(v bool) string {
+	if v {
+		return "да"
+	}
+	return "нет"
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRepositoryLineForDerivedDistros(t *testing.T) {
+	const line = "https://download.docker.com/linux/{{.RepoDistro}} {{.Codename}} stable"
+	tests := []struct {
+		name      string
+		osRelease string
+		want      string
+	}{
+		{"ubuntu", "ID=ubuntu\nID_LIKE=debian\nVERSION_CODENAME=noble\nUBUNTU_CODENAME=noble\n", "https://download.docker.com/linux/ubuntu noble stable"},
+		{"debian", "ID=debian\nVERSION_CODENAME=bookworm\n", "https://download.docker.com/linux/debian bookworm stable"},
+		{"linuxmint", "ID=linuxmint\nID_LIKE=\"ubuntu debian\"\nVERSION_CODENAME=virginia\nUBUNTU_CODENAME=jammy\n", "https://download.docker.com/linux/ubuntu jammy stable"},
+		{"pop", "ID=pop\nID_LIKE=\"ubuntu debian\"\nVERSION_CODENAME=jammy\nUBUNTU_CODENAME=jammy\n", "https://download.docker.com/linux/ubuntu jammy stable"},
+		{"lmde", "ID=linuxmint\nID_LIKE=debian\nVERSION_CODENAME=faye\nDEBIAN_CODENAME=bookworm\n", "https://download.docker.com/linux/debian bookworm stable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeExecutor(t)
+			if err := os.WriteFile(osReleasePath, []byte(tt.osRelease), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			release, err := readOSRelease(osReleasePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := Platform{OS: "linux"}
+			p.applyOSRelease(release)
+			got, err := p.expand(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("%q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlatformJSONOutput(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Distro: "linuxmint", DistroLike: []string{"ubuntu", "debian"}, Codename: "jammy", Arch: "amd64"})
+	useFakeExecutor(t, "apt-get")
+	var out bytes.Buffer
+	resultOutput, outputFormat = &out, "json"
+	t.Cleanup(func() { resultOutput, outputFormat = os.Stdout, "text" })
+
+	if err := platformRun(platformCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	var got PlatformInfo
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("вывод не JSON: %v\n%s", err, out.String())
+	}
+	want := PlatformInfo{OS: "linux", Distro: "linuxmint", DistroLike: []string{"ubuntu", "debian"}, RepoDistro: "ubuntu", Codename: "jammy", Arch: "amd64", PackageManager: "apt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("platform = %+v, ожидалось %+v", got, want)
+	}
+}
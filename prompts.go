@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// multiSelectAction — служебное действие в списке с флажками
+type multiSelectAction int
+
+const (
+	toggleItem multiSelectAction = iota
+	finishSelection
+	checkAll
+	uncheckAll
+)
+
+// multiSelectItem — пункт списка с флажком
+type multiSelectItem struct {
+	Name      string
+	Checked   bool
+	Installed bool
+	action    multiSelectAction
+}
+
+// Mark возвращает флажок пункта для шаблона
+func (i *multiSelectItem) Mark() string {
+	if i.action != toggleItem {
+		return "   "
+	}
+	if i.Checked {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// multiSelectPageSize — сколько пунктов показывается одновременно
+const multiSelectPageSize = 15
+
+// multiSelect показывает список с флажками и возвращает отмеченные пункты в исходном порядке.
+// Enter переключает флажок, «/» включает поиск, пункт «Готово» завершает выбор.
+// Уже установленные инструменты отмечаются заранее.
+func multiSelect(ctx context.Context, label string, options []string, osType string) []string {
+	done := &multiSelectItem{Name: "Готово", action: finishSelection}
+	items := []*multiSelectItem{
+		done,
+		{Name: "Выбрать все", action: checkAll},
+		{Name: "Снять все", action: uncheckAll},
+	}
+	for _, option := range options {
+		installed := false
+		if tool, ok := availableTools[option]; ok {
+			installed = tool.present(ctx, osType)
+		}
+		items = append(items, &multiSelectItem{Name: option, Checked: installed, Installed: installed})
+	}
+
+	size := len(items)
+	if size > multiSelectPageSize {
+		size = multiSelectPageSize
+	}
+
+	cursor, scroll := 0, 0
+	for {
+		prompt := promptui.Select{
+			Label:        fmt.Sprintf("%s (Enter — отметить, / — поиск), выбрано: %d", label, countChecked(items)),
+			Items:        items,
+			Size:         size,
+			HideSelected: true,
+			Searcher: func(input string, index int) bool {
+				item := items[index]
+				return item.action != toggleItem || strings.Contains(strings.ToLower(item.Name), strings.ToLower(input))
+			},
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}",
+				Active:   "\u25B6 {{ .Mark }} {{ .Name | cyan }}{{ if .Installed }} {{ \"(установлен)\" | faint }}{{ end }}",
+				Inactive: "  {{ .Mark }} {{ .Name }}{{ if .Installed }} {{ \"(установлен)\" | faint }}{{ end }}",
+			},
+		}
+
+		idx, _, err := prompt.RunCursorAt(cursor, scroll)
+		if err != nil {
+			log.Fatalf("Ошибка выбора: %v", err)
+		}
+
+		item := items[idx]
+		switch item.action {
+		case finishSelection:
+			var selected []string
+			for _, item := range items {
+				if item.action == toggleItem && item.Checked {
+					selected = append(selected, item.Name)
+				}
+			}
+			return selected
+		case checkAll, uncheckAll:
+			for _, other := range items {
+				other.Checked = item.action == checkAll && other.action == toggleItem
+			}
+		default:
+			item.Checked = !item.Checked
+		}
+
+		cursor = idx
+		if cursor >= size {
+			scroll = cursor - size + 1
+		} else {
+			scroll = 0
+		}
+	}
+}
+
+// countChecked возвращает число отмеченных пунктов
+func countChecked(items []*multiSelectItem) int {
+	count := 0
+	for _, item := range items {
+		if item.action == toggleItem && item.Checked {
+			count++
+		}
+	}
+	return count
+}
+
+// confirmSelection показывает сводку выбора и запрашивает подтверждение
+func confirmSelection(ctx context.Context, action Action, stack Stack, ide []string, tools []string, osType string) error {
+	fmt.Printf("\n%s — стек %s\n", action, stack)
+	printSelection(ctx, "IDE", ide, osType)
+	printSelection(ctx, "Инструменты", tools, osType)
+	fmt.Println()
+
+	if len(ide)+len(tools) == 0 {
+		return errors.New("ничего не выбрано")
+	}
+	if dryRun {
+		return nil
+	}
+
+	prompt := promptui.Prompt{
+		Label:     "Продолжить",
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return fmt.Errorf("%s: операция отменена", action)
+	}
+	return nil
+}
+
+// printSelection выводит группу выбранных инструментов с их текущим состоянием
+func printSelection(ctx context.Context, title string, names []string, osType string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Printf("  %s:\n", title)
+	for _, name := range names {
+		state := "не установлен"
+		if tool, ok := availableTools[name]; ok && tool.present(ctx, osType) {
+			state = "установлен"
+		}
+		fmt.Printf("    • %s (%s)\n", name, state)
+	}
+}
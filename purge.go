@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// purgeUninstall включает полную очистку при удалении: репозитории, ключи и файлы,
+// добавленные при установке, а для приложений Homebrew — их настройки
+var purgeUninstall bool
+
+// purgeCommands возвращает команды, убирающие то, что было добавлено при установке инструмента,
+// и замечания о том, что убрать автоматически нельзя
+func purgeCommands(record InstallRecord) (commands, notes []string) {
+	for _, path := range record.Paths {
+		commands = append(commands, "rm -rf "+shellQuote(path))
+	}
+	for _, repo := range record.Repositories {
+		if strings.HasPrefix(repo, "/") {
+			commands = append(commands, "sudo rm -f "+repo)
+		} else {
+			commands = append(commands, "sudo add-apt-repository --remove "+shellQuote(repo))
+		}
+	}
+	for _, key := range record.Keys {
+		if strings.HasPrefix(key, "/") {
+			commands = append(commands, "sudo rm -f "+key)
+		} else {
+			notes = append(notes, fmt.Sprintf("ключ из %s добавлен через apt-key; найдите его в apt-key list и удалите вручную", key))
+		}
+	}
+	return commands, notes
+}
+
+// purgeTool удаляет репозитории, ключи и файлы, которые были добавлены при установке инструмента.
+// Без записи в файле состояния неизвестно, что добавляли мы, поэтому ничего не удаляется.
+func purgeTool(ctx context.Context, tool Tool, osType string) error {
+	record, ok, err := installRecord(tool.Name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Printf("%s: нет записи об установке, дополнительные файлы не удаляются.\n", tool.Description)
+		return nil
+	}
+	if record.Backend == "hook" {
+		// Файлы встроенной функции — это и есть установленный инструмент, их удалил uninstall
+		record.Paths = nil
+	}
+
+	commands, notes := purgeCommands(record)
+	for _, command := range commands {
+		if err := runCommand(ctx, command, osType); err != nil {
+			return fmt.Errorf("ошибка очистки %s: %v", tool.Description, err)
+		}
+	}
+	for _, note := range notes {
+		fmt.Printf("%s: %s\n", tool.Description, note)
+	}
+	return nil
+}
+
+// shellQuote заключает строку в одинарные кавычки для sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
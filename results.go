@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// keepGoing включает режим, в котором ошибка одного инструмента не останавливает остальные
+var keepGoing bool
+
+// Outcome — итог операции над одним инструментом
+type Outcome string
+
+const (
+	OutcomeSucceeded Outcome = "выполнено"
+	OutcomeSkipped   Outcome = "пропущено"
+	OutcomeFailed    Outcome = "ошибка"
+)
+
+// ToolResult — результат операции над инструментом для итоговой таблицы
+type ToolResult struct {
+	Tool    string  `json:"tool"`
+	Outcome Outcome `json:"outcome"`
+	// Detail — причина пропуска или текст ошибки
+	Detail string `json:"detail,omitempty"`
+	err    error
+}
+
+// skipError сообщает, что операция не потребовалась, например инструмент уже установлен.
+// Это не ошибка: в итоговой таблице инструмент попадает в пропущенные.
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return e.reason
+}
+
+// errNotInstalled — пропуск обновления или удаления инструмента, которого нет в системе
+var errNotInstalled = skipped("не установлен")
+
+// skipped возвращает отметку о пропуске инструмента с причиной
+func skipped(format string, args ...interface{}) error {
+	return &skipError{reason: fmt.Sprintf(format, args...)}
+}
+
+// newToolResult классифицирует результат op: пропуск, ошибка или успех
+func newToolResult(name string, err error) ToolResult {
+	var skip *skipError
+	switch {
+	case err == nil:
+		return ToolResult{Tool: name, Outcome: OutcomeSucceeded}
+	case errors.As(err, &skip):
+		return ToolResult{Tool: name, Outcome: OutcomeSkipped, Detail: skip.reason}
+	default:
+		return ToolResult{Tool: name, Outcome: OutcomeFailed, Detail: err.Error(), err: err}
+	}
+}
+
+// failures возвращает ошибки инструментов, завершившихся неудачно
+func failures(results []ToolResult) []error {
+	var errs []error
+	for _, r := range results {
+		if r.Outcome == OutcomeFailed {
+			errs = append(errs, r.err)
+		}
+	}
+	return errs
+}
+
+// printResults выводит итоговую таблицу по инструментам в выбранном формате
+func printResults(w io.Writer, format string, results []ToolResult) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(append([]ToolResult{}, results...))
+	}
+
+	counts := make(map[Outcome]int)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ИНСТРУМЕНТ\tРЕЗУЛЬТАТ\tПОДРОБНОСТИ")
+	for _, r := range results {
+		counts[r.Outcome]++
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Tool, r.Outcome, dash(r.Detail))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "Итого: выполнено %d, пропущено %d, ошибок %d\n",
+		counts[OutcomeSucceeded], counts[OutcomeSkipped], counts[OutcomeFailed])
+	return err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"time"
+)
+
+// retryAttempts — сколько раз повторять команду после временной ошибки
+var retryAttempts = 3
+
+// retryDelay — пауза перед первым повтором; каждая следующая вдвое длиннее, но не больше maxRetryDelay
+var retryDelay = 2 * time.Second
+
+const maxRetryDelay = time.Minute
+
+// transientFailure — признак временной ошибки: сетевой сбой или занятая блокировка,
+// после которых команду имеет смысл повторить
+type transientFailure struct {
+	// reason — описание для лога
+	reason  string
+	pattern *regexp.Regexp
+}
+
+// transientFailures перечисляет известные сообщения apt, dnf, brew, choco, curl, wget и git
+var transientFailures = []transientFailure{
+	{"apt: база пакетов заблокирована", regexp.MustCompile(`Could not get lock|Unable to acquire the dpkg frontend lock|is another process using it`)},
+	{"apt: ошибка загрузки", regexp.MustCompile(`Temporary failure resolving|Failed to fetch|Unable to fetch some archives|Hash Sum mismatch|Some index files failed to download`)},
+	{"dnf: ошибка загрузки", regexp.MustCompile(`Cannot download|Failed to download metadata|Errors during downloading metadata|Curl error \(\d+\)|Cannot retrieve repository metadata`)},
+	{"dnf: база пакетов заблокирована", regexp.MustCompile(`Waiting for process with pid|Another app is currently holding the yum lock`)},
+	{"brew: ошибка загрузки", regexp.MustCompile(`Failed to download resource|Download failed|Failure while executing; .*curl`)},
+	{"brew: запущен другой процесс", regexp.MustCompile(`Another active Homebrew .* process is already in progress|has already locked`)},
+	{"choco: ошибка сети", regexp.MustCompile(`The remote name could not be resolved|Unable to connect to the remote server|The operation has timed out|\(503\) Server Unavailable|\(502\) Bad Gateway`)},
+	{"choco: файл занят", regexp.MustCompile(`being used by another process`)},
+	{"curl: ошибка сети", regexp.MustCompile(`curl: \((6|7|18|28|35|52|55|56)\)`)},
+	{"wget: ошибка сети", regexp.MustCompile(`unable to resolve host address|Temporary failure in name resolution|Connection refused|Read error .* in headers|Connection timed out`)},
+	{"git: ошибка сети", regexp.MustCompile(`Could not resolve host|early EOF|RPC failed|The remote end hung up unexpectedly|Failed to connect to .* port`)},
+}
+
+// classifyFailure определяет, временная ли ошибка команды, по ее выводу
+func classifyFailure(err error) (string, bool) {
+	var failure *commandFailure
+	if !errors.As(err, &failure) {
+		return "", false
+	}
+	for _, t := range transientFailures {
+		if t.pattern.MatchString(failure.output) {
+			return t.reason, true
+		}
+	}
+	return "", false
+}
+
+// runWithRetry выполняет run и повторяет его с экспоненциальной паузой, пока ошибка временная.
+// Ошибки тайм-аута и отмены не повторяются.
+func runWithRetry(ctx context.Context, command string, run func() error) (int, error) {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || ctx.Err() != nil || attempt > retryAttempts {
+			return attempt, err
+		}
+		reason, transient := classifyFailure(err)
+		if !transient {
+			return attempt, err
+		}
+
+		log.Printf("Временная ошибка (%s), попытка %d из %d, повтор через %v: %s\n", reason, attempt, retryAttempts+1, delay, command)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return attempt, err
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
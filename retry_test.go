@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func useFastRetries(t *testing.T) {
+	t.Helper()
+	prevAttempts, prevDelay := retryAttempts, retryDelay
+	retryAttempts, retryDelay = 3, time.Millisecond
+	t.Cleanup(func() { retryAttempts, retryDelay = prevAttempts, prevDelay })
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		output string
+		reason string
+	}{
+		{"E: Could not get lock /var/lib/dpkg/lock-frontend. It is held by process 1234 (unattended-upgr)", "apt: база пакетов заблокирована"},
+		{"Err:1 http://deb.debian.org/debian bookworm InRelease\n  Temporary failure resolving 'deb.debian.org'", "apt: ошибка загрузки"},
+		{"Error: Failed to download metadata for repo 'fedora'", "dnf: ошибка загрузки"},
+		{"Error: Another active Homebrew update process is already in progress.", "brew: запущен другой процесс"},
+		{"The remote name could not be resolved: 'community.chocolatey.org'", "choco: ошибка сети"},
+		{"curl: (28) Operation timed out after 30001 milliseconds", "curl: ошибка сети"},
+		{"fatal: unable to access 'https://github.com/AstroNvim/AstroNvim/': Could not resolve host: github.com", "git: ошибка сети"},
+	}
+	for _, tt := range tests {
+		reason, ok := classifyFailure(&commandFailure{err: errors.New("exit status 1"), output: tt.output})
+		if !ok || reason != tt.reason {
+			t.Errorf("%q: получено %q, ожидалось %q", tt.output, reason, tt.reason)
+		}
+	}
+
+	if _, ok := classifyFailure(&commandFailure{err: errors.New("exit status 100"), output: "E: Unable to locate package nosuchpkg"}); ok {
+		t.Error("отсутствующий пакет не должен считаться временной ошибкой")
+	}
+}
+
+func TestRunCommandRetriesTransientFailure(t *testing.T) {
+	useFastRetries(t)
+	fake := useFakeExecutor(t)
+	fake.failOn = []string{"apt-get update"}
+	fake.failOutput = "E: Could not get lock /var/lib/dpkg/lock-frontend"
+	fake.failTimes = 2
+
+	if err := runCommand(context.Background(), "sudo apt-get update", "linux"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.runs) != 3 {
+		t.Errorf("выполнено %d попыток, ожидалось 3", len(fake.runs))
+	}
+}
+
+func TestRunCommandGivesUpAfterRetries(t *testing.T) {
+	useFastRetries(t)
+	fake := useFakeExecutor(t)
+	fake.failOn = []string{"curl"}
+	fake.failOutput = "curl: (6) Could not resolve host: get.sdkman.io"
+
+	err := runCommand(context.Background(), "curl -fsSL https://get.sdkman.io | bash", "linux")
+	if err == nil || !strings.Contains(err.Error(), "попыток: 4") {
+		t.Fatalf("ошибка %v", err)
+	}
+	if len(fake.runs) != 4 {
+		t.Errorf("выполнено %d попыток, ожидалось 4", len(fake.runs))
+	}
+}
+
+func TestRunCommandDoesNotRetryPermanentFailure(t *testing.T) {
+	useFastRetries(t)
+	fake := useFakeExecutor(t)
+	fake.failOn = []string{"nosuchpkg"}
+	fake.failOutput = "E: Unable to locate package nosuchpkg"
+
+	if err := runCommand(context.Background(), "sudo apt-get install -y nosuchpkg", "linux"); err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	if len(fake.runs) != 1 {
+		t.Errorf("выполнено %d попыток, ожидалась 1", len(fake.runs))
+	}
+}
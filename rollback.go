@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// atomicInstall включает транзакционную установку: при ошибке все изменения запуска отменяются
+var atomicInstall bool
+
+// checkAtomic проверяет, что --atomic не сочетается с несовместимыми флагами
+func checkAtomic() error {
+	if atomicInstall && keepGoing {
+		return fmt.Errorf("флаги --atomic и --keep-going несовместимы: при --atomic первая ошибка отменяет всю установку")
+	}
+	return nil
+}
+
+// undoStep — обратное действие для одного изменения, сделанного при установке
+type undoStep struct {
+	tool Tool
+	// order — порядковый номер команды, которую отменяет шаг
+	order uint64
+	// action — что отменяется, для вывода
+	action string
+	// run выполняет отмену; nil означает, что отменить автоматически нельзя
+	run func(ctx context.Context) error
+	// note — почему шаг нельзя отменить автоматически
+	note string
+}
+
+// RollbackResult — итог отмены одного изменения для вывода
+type RollbackResult struct {
+	Tool     string `json:"tool"`
+	Action   string `json:"action"`
+	Reverted bool   `json:"reverted"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// rollbackJournal запоминает обратные действия для изменений текущего запуска
+type rollbackJournal struct {
+	mu    sync.Mutex
+	steps []undoStep
+	// installed — инструменты, установленные в этом запуске, для очистки файла состояния
+	installed []Tool
+}
+
+// add записывает обратные действия для каждой выполненной команды инструмента после попытки его установки.
+// Команда, подключившая репозиторий или ключ, отменяется их удалением, последняя команда установленного
+// инструмента — его удалением. Команды, которые не меняют систему, отменять не нужно. Для остальных
+// команд обратное действие неизвестно, и в итоге отката они показываются неотмененными.
+func (j *rollbackJournal) add(tool Tool, rec *installRecorder, installed bool, osType string) {
+	record := rec.record(tool.Name, "")
+	rec.mu.Lock()
+	order := append([]uint64(nil), rec.order...)
+	rec.mu.Unlock()
+
+	uninstall := func(order uint64) undoStep {
+		tool := tool
+		tool.InstalledPackage = record.Package
+		tool.InstalledRelease = record.Release
+		return undoStep{tool: tool, order: order, action: "удаление инструмента", run: func(ctx context.Context) error {
+			return executeCommand(ctx, osType, "uninstall", tool)
+		}}
+	}
+
+	var steps []undoStep
+	// Источник относится к первой упомянувшей его команде: строка репозитория ссылается на ключ через signed-by
+	claimed := make(map[string]bool)
+	for i, command := range record.Commands {
+		added := InstallRecord{
+			Repositories: claimSources(matchAll([]string{command}, repositoryPatterns), record.Repositories, claimed),
+			Keys:         claimSources(matchAll([]string{command}, keyPatterns), record.Keys, claimed),
+		}
+		steps = append(steps, purgeSteps(tool, order[i], added, osType)...)
+		last := i == len(record.Commands)-1
+		switch {
+		case last && installed && record.Backend != "hook":
+			steps = append(steps, uninstall(order[i]))
+		case len(added.Repositories)+len(added.Keys) > 0:
+		case record.Backend == "hook" && len(record.Paths) > 0:
+			// Команды встроенной функции отменяются удалением созданных ею файлов
+		case needsNoUndo(command):
+		default:
+			steps = append(steps, undoStep{tool: tool, order: order[i], action: command, note: "обратное действие неизвестно"})
+		}
+	}
+	// Файлы встроенной функции появляются к концу установки и при откате удаляются первыми
+	end := commandOrder.Add(1)
+	steps = append(steps, purgeSteps(tool, end, InstallRecord{Paths: record.Paths}, osType)...)
+	if len(record.Commands) == 0 && installed && record.Backend != "hook" {
+		steps = append(steps, uninstall(end))
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.steps = append(j.steps, steps...)
+	if installed {
+		j.installed = append(j.installed, tool)
+	}
+}
+
+var (
+	// indexRefreshPattern находит обновление индекса пакетов: оно ничего не устанавливает
+	indexRefreshPattern = regexp.MustCompile(`^(?:sudo\s+)?(?:apt(?:-get)?\s+update|(?:dnf|yum)\s+makecache|zypper\s+(?:--non-interactive\s+)?ref(?:resh)?)$`)
+	// outputPattern находит файлы, в которые пишет команда: перенаправление и -o/-O/--output у curl и wget
+	outputPattern = regexp.MustCompile(`(?:>+|\s-[a-zA-Z]*[oO]|\s--output(?:-document)?[=\s])\s*([^\s|;&]+)`)
+)
+
+// needsNoUndo проверяет, что команда не меняет систему: обновляет индекс пакетов, скачивает
+// или преобразует файл во временный или рабочий каталог либо удаляет такой файл
+func needsNoUndo(command string) bool {
+	command = strings.TrimSpace(command)
+	if indexRefreshPattern.MatchString(command) {
+		return true
+	}
+	if fields := strings.Fields(command); len(fields) > 1 && fields[0] == "rm" {
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !localFile(field) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, part := range strings.Split(command, "|") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			return false
+		}
+		switch fields[0] {
+		case "curl", "wget":
+		case "gpg":
+			if !contains(fields, "--dearmor") {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	for _, match := range outputPattern.FindAllStringSubmatch(command, -1) {
+		if !localFile(match[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// localFile проверяет, что путь указывает на рабочий или временный каталог, стандартный вывод или /dev/null
+func localFile(path string) bool {
+	path = strings.Trim(path, `'"`)
+	switch {
+	case path == "-" || path == "/dev/null":
+		return true
+	case strings.HasPrefix(path, "/tmp/"):
+		return true
+	case strings.HasPrefix(path, "/"), strings.HasPrefix(path, "~"), strings.HasPrefix(path, "$"), strings.Contains(path, ".."):
+		return false
+	}
+	return true
+}
+
+// purgeSteps возвращает шаги удаления репозиториев, ключей и файлов из записи
+func purgeSteps(tool Tool, order uint64, record InstallRecord, osType string) []undoStep {
+	var steps []undoStep
+	commands, notes := purgeCommands(record)
+	for _, command := range commands {
+		steps = append(steps, undoStep{tool: tool, order: order, action: command, run: func(ctx context.Context) error {
+			return runCommand(ctx, command, osType)
+		}})
+	}
+	for _, note := range notes {
+		steps = append(steps, undoStep{tool: tool, order: order, action: "ключ apt-key", note: note})
+	}
+	return steps
+}
+
+// claimSources оставляет источники из sources, которые есть в added и еще не отнесены к другой команде.
+// Существовавшие до установки источники в added не попадают и не отменяются.
+func claimSources(sources, added []string, claimed map[string]bool) []string {
+	var result []string
+	for _, source := range sources {
+		if contains(added, source) && !claimed[source] {
+			claimed[source] = true
+			result = append(result, source)
+		}
+	}
+	return result
+}
+
+// rollback отменяет записанные изменения в порядке, обратном выполнению команд, и выводит,
+// что отменено, а что нет. Отмена выполняется и после прерывания: иначе система останется
+// в промежуточном состоянии. Запись об установке остается у инструментов, изменения которых
+// отменены не полностью: по ней их можно удалить позже через uninstall --purge.
+func (j *rollbackJournal) rollback(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.steps) == 0 {
+		return nil
+	}
+	ctx = context.WithoutCancel(ctx)
+	fmt.Println("Отмена изменений, сделанных при установке...")
+
+	// Шаги одной команды отменяются в обратном порядке записи
+	steps := make([]undoStep, 0, len(j.steps))
+	for i := len(j.steps) - 1; i >= 0; i-- {
+		steps = append(steps, j.steps[i])
+	}
+	sort.SliceStable(steps, func(a, b int) bool { return steps[a].order > steps[b].order })
+
+	results := make([]RollbackResult, 0, len(steps))
+	failed := 0
+	incomplete := make(map[string]bool)
+	for _, step := range steps {
+		result := RollbackResult{Tool: step.tool.Name, Action: step.action}
+		if step.run == nil {
+			result.Detail = step.note
+		} else if err := step.run(ctx); err != nil {
+			result.Detail = err.Error()
+		} else {
+			result.Reverted = true
+		}
+		if !result.Reverted {
+			failed++
+			incomplete[step.tool.Name] = true
+		}
+		results = append(results, result)
+	}
+	for _, tool := range j.installed {
+		if !incomplete[tool.Name] {
+			forgetInstall(tool)
+		}
+	}
+
+	if err := printRollback(resultOutput, outputFormat, results); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("откат выполнен не полностью: не отменено изменений %d из %d", failed, len(results))
+	}
+	return nil
+}
+
+// printRollback выводит итог отмены в выбранном формате
+func printRollback(w io.Writer, format string, results []RollbackResult) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ИНСТРУМЕНТ\tОТМЕНА\tРЕЗУЛЬТАТ")
+	for _, r := range results {
+		outcome := "отменено"
+		if !r.Reverted {
+			outcome = "не отменено: " + r.Detail
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Tool, r.Action, outcome)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAtomicInstallRollsBackInReverseOrder(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	fake.failOn = []string{"docker-ce"}
+	useTools(t, map[string]Tool{
+		"jq": jqTool,
+		"Visual Studio Code": {DetectBinary: "code", Description: "Visual Studio Code", Requires: []string{"jq"}, Steps: map[string][]string{"apt": {
+			"sudo apt install -y ca-certificates curl software-properties-common",
+			"wget -qO- https://packages.microsoft.com/keys/microsoft.asc | gpg --dearmor > packages.microsoft.gpg",
+			"sudo install -D -o root -g root -m 644 packages.microsoft.gpg /etc/apt/keyrings/packages.microsoft.gpg",
+			`sudo sh -c 'echo "deb [signed-by=/etc/apt/keyrings/packages.microsoft.gpg] https://packages.microsoft.com/repos/code stable main" > /etc/apt/sources.list.d/vscode.list'`,
+			"rm -f packages.microsoft.gpg",
+			"sudo apt update",
+			"sudo apt install -y code",
+		}}},
+		"Docker": {DetectBinary: "docker", Description: "Docker", Requires: []string{"Visual Studio Code"}, Steps: map[string][]string{"apt": {
+			`sudo sh -c 'echo "deb https://download.docker.com/linux/ubuntu jammy stable" > /etc/apt/sources.list.d/docker.list'`,
+			"sudo apt install -y docker-ce",
+		}}},
+	})
+	atomicInstall = true
+	t.Cleanup(func() { atomicInstall = false })
+	var out bytes.Buffer
+	resultOutput = &out
+	t.Cleanup(func() { resultOutput = os.Stdout })
+
+	err := installStack(context.Background(), EssentialStack, nil, []string{"jq", "Visual Studio Code", "Docker"}, "linux")
+	if err == nil || !strings.Contains(err.Error(), "откат выполнен не полностью: не отменено изменений 2 из 6") {
+		t.Fatalf("ошибка %v", err)
+	}
+
+	got := fake.commands()
+	// Репозиторий VS Code подключен после ключа, поэтому удаляется раньше него
+	want := []string{
+		"sudo rm -f /etc/apt/sources.list.d/docker.list",
+		"sudo rm -f /etc/apt/sources.list.d/vscode.list",
+		"sudo rm -f /etc/apt/keyrings/packages.microsoft.gpg",
+		"sudo apt-get remove -y jq",
+	}
+	if len(got) < len(want) || !reflect.DeepEqual(got[len(got)-len(want):], want) {
+		t.Errorf("команды отмены %q, ожидались в конце %q", got, want)
+	}
+
+	table := strings.Join(strings.Fields(out.String()), " ")
+	for _, expected := range []string{
+		"Visual Studio Code удаление инструмента не отменено: в каталоге нет пакета для apt",
+		"Visual Studio Code sudo apt install -y ca-certificates curl software-properties-common не отменено: обратное действие неизвестно",
+		"jq удаление инструмента отменено",
+	} {
+		if !strings.Contains(table, expected) {
+			t.Errorf("в итоге отмены нет %q:\n%s", expected, out.String())
+		}
+	}
+	// Удаление VS Code не удалось, поэтому запись о нем остается для uninstall --purge
+	state, _ := loadState()
+	if _, ok := state.Tools["jq"]; ok {
+		t.Errorf("в состоянии осталась отмененная установка jq: %+v", state.Tools)
+	}
+	if _, ok := state.Tools["Visual Studio Code"]; !ok {
+		t.Errorf("запись о не полностью отмененной установке VS Code удалена: %+v", state.Tools)
+	}
+}
+
+func TestNeedsNoUndo(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"sudo apt update", true},
+		{"sudo dnf makecache", true},
+		{"rm -f packages.microsoft.gpg", true},
+		{"wget -qO- https://packages.microsoft.com/keys/microsoft.asc | gpg --dearmor > packages.microsoft.gpg", true},
+		{"curl -fsSL -o /tmp/go.tar.gz https://go.dev/dl/go1.22.5.linux-amd64.tar.gz", true},
+		{"sudo rm -f /etc/apt/sources.list.d/vscode.list", false},
+		{"rm -rf ~/.oh-my-zsh", false},
+		{"curl -fsSL https://get.sdkman.io | bash", false},
+		{"curl -fsSL https://download.docker.com/linux/ubuntu/gpg | sudo gpg --dearmor -o /etc/apt/keyrings/docker.gpg", false},
+		{"wget -O /usr/local/bin/tool https://example.com/tool", false},
+		{"sudo apt install -y ca-certificates curl software-properties-common", false},
+	}
+	for _, tt := range tests {
+		if got := needsNoUndo(tt.command); got != tt.want {
+			t.Errorf("needsNoUndo(%q) = %v, ожидалось %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestAtomicConflictsWithKeepGoing(t *testing.T) {
+	atomicInstall, keepGoing = true, true
+	t.Cleanup(func() { atomicInstall, keepGoing = false, false })
+	if err := checkAtomic(); err == nil {
+		t.Fatal("--atomic принят вместе с --keep-going")
+	}
+}
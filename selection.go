@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// assumeYes отключает все интерактивные запросы: вместо вопроса программа завершается с ошибкой
+var assumeYes bool
+
+// selectionFlags содержит значения флагов, описывающих выбор пользователя
+type selectionFlags struct {
+	stack string
+	ide   []string
+}
+
+// interactive сообщает, можно ли задавать пользователю вопросы.
+// Если инструменты переданы аргументами, команда считается неинтерактивной.
+func interactive(args []string) bool {
+	return !assumeYes && len(args) == 0
+}
+
+// errPromptDisabled формирует ошибку для случая, когда требуется ввод, но запросы отключены.
+// missing — согласованная фраза о том, чего не хватает, например "не указан стек".
+func errPromptDisabled(missing, hint string) error {
+	return fmt.Errorf("%s, а интерактивный выбор отключен; %s", missing, hint)
+}
+
+// parseStack конвертирует строку в Stack, возвращая ошибку для неизвестного стека
+func parseStack(s string) (Stack, error) {
+	for _, stack := range allStacks {
+		if strings.EqualFold(string(stack), s) {
+			return stack, nil
+		}
+	}
+	return "", fmt.Errorf("неизвестный стек %q, доступные: %s", s, joinStacks(allStacks))
+}
+
+// joinStacks возвращает список стеков через запятую
+func joinStacks(stacks []Stack) string {
+	names := make([]string, 0, len(stacks))
+	for _, stack := range stacks {
+		names = append(names, string(stack))
+	}
+	return strings.Join(names, ", ")
+}
+
+// resolveToolNames сопоставляет имена из командной строки с ключами availableTools.
+// Имя можно указать как название инструмента (без учета регистра) или как его команду,
+// а после @ — ограничение версии: node@20, go@1.22, "python3@>=3.11".
+func resolveToolNames(names []string) ([]string, error) {
+	var resolved []string
+	var unknown []string
+
+	for _, spec := range names {
+		name, pin := splitToolSpec(spec)
+		key, ok := lookupToolName(name)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if pin != "" {
+			if _, err := parseConstraint(pin); err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			if !availableTools[key].versionable() {
+				return nil, fmt.Errorf("для %s выбор версии не поддерживается", key)
+			}
+		}
+		resolved = append(resolved, joinToolSpec(key, pin))
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("неизвестные инструменты: %s", strings.Join(unknown, ", "))
+	}
+	return resolved, nil
+}
+
+// lookupToolName ищет инструмент по названию или команде
+func lookupToolName(name string) (string, bool) {
+	if _, ok := availableTools[name]; ok {
+		return name, true
+	}
+	for key, tool := range availableTools {
+		if strings.EqualFold(key, name) || strings.EqualFold(tool.DetectBinary, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// resolveStack возвращает стек из флага или запрашивает его у пользователя
+func resolveStack(flags selectionFlags, args []string) (Stack, error) {
+	if flags.stack != "" {
+		return parseStack(flags.stack)
+	}
+	if len(args) > 0 {
+		// Стек нужен только для подсказок, при явном списке инструментов его можно не указывать
+		return EssentialStack, nil
+	}
+	if !interactive(args) {
+		return "", errPromptDisabled("не указан стек", "укажите --stack или перечислите инструменты аргументами")
+	}
+	return StringToStack(selectStack()), nil
+}
+
+// resolveTools возвращает инструменты из аргументов или запрашивает их у пользователя
+func resolveTools(ctx context.Context, stack Stack, args []string) ([]string, error) {
+	if len(args) > 0 {
+		return resolveToolNames(args)
+	}
+	if !interactive(args) {
+		return nil, errPromptDisabled("не указан список инструментов", "перечислите инструменты аргументами")
+	}
+	return selectStackTools(ctx, string(stack)), nil
+}
+
+// resolveIDE возвращает IDE из флага или запрашивает их у пользователя
+func resolveIDE(ctx context.Context, flags selectionFlags, stack Stack, args []string) ([]string, error) {
+	if len(flags.ide) > 0 {
+		return resolveToolNames(flags.ide)
+	}
+	if !interactive(args) {
+		// В неинтерактивном режиме IDE устанавливаются только по явному флагу --ide
+		return nil, nil
+	}
+	return selectIDE(ctx, stack), nil
+}
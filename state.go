@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stateVersion — версия формата файла состояния
+const stateVersion = 1
+
+// InstallRecord описывает инструмент, установленный DevOrchestrator
+type InstallRecord struct {
+	Tool string `json:"tool"`
+	// Version — версия сразу после установки, если ее удалось определить
+	Version string `json:"version,omitempty"`
+	// Backend — чем установлен инструмент: пакетный менеджер, менеджер версий или hook
+	Backend string `json:"backend"`
+	// Package — установленный пакет менеджера (openjdk-21-jdk, node@20, кандидат SDKMAN),
+	// Pin — выбранная версия; удаляется именно то, что было установлено
+	Package string `json:"package,omitempty"`
+	Pin     string `json:"pin,omitempty"`
+	// Release — идентификатор установленной версии в менеджере версий (21.0.4-tem, v20.18.0)
+	Release string `json:"release,omitempty"`
+	// Commands — выполненные команды в порядке запуска
+	Commands []string `json:"commands"`
+	// Repositories и Keys — подключенные при установке репозитории и ключи подписи
+	Repositories []string `json:"repositories,omitempty"`
+	Keys         []string `json:"keys,omitempty"`
+	// Paths — файлы и каталоги, созданные встроенной функцией установки
+	Paths       []string  `json:"paths,omitempty"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// State — содержимое файла состояния
+type State struct {
+	Version int                      `json:"version"`
+	Tools   map[string]InstallRecord `json:"tools"`
+}
+
+// forceUninstall разрешает удалять инструменты, установленные не через DevOrchestrator
+var forceUninstall bool
+
+// stateMu защищает файл состояния: инструменты устанавливаются параллельно
+var stateMu sync.Mutex
+
+// stateFile возвращает путь к файлу состояния
+func stateFile() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "devorchestrator", "state.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "state", "devorchestrator", "state.json")
+}
+
+// loadState читает файл состояния; отсутствующий файл — пустое состояние
+func loadState() (*State, error) {
+	state := &State{Version: stateVersion, Tools: make(map[string]InstallRecord)}
+	path := stateFile()
+	if path == "" {
+		return state, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения состояния: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("ошибка разбора состояния %s: %v", path, err)
+	}
+	if state.Tools == nil {
+		state.Tools = make(map[string]InstallRecord)
+	}
+	return state, nil
+}
+
+// save записывает состояние через временный файл, чтобы прерванная запись не испортила его
+func (s *State) save() error {
+	path := stateFile()
+	if path == "" {
+		return fmt.Errorf("не удалось определить домашний каталог для файла состояния")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("ошибка создания каталога состояния: %v", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("ошибка записи состояния: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("ошибка записи состояния: %v", err)
+	}
+	return nil
+}
+
+// updateState читает состояние, изменяет его и сразу сохраняет
+func updateState(change func(*State)) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	change(state)
+	return state.save()
+}
+
+// forgetInstall удаляет запись об инструменте после его удаления
+func forgetInstall(tool Tool) {
+	if err := updateState(func(s *State) { delete(s.Tools, tool.Name) }); err != nil {
+		log.Printf("Не удалось обновить состояние для %s: %v\n", tool.Description, err)
+	}
+}
+
+// installRecord возвращает запись об установке инструмента из файла состояния
+func installRecord(name string) (InstallRecord, bool, error) {
+	stateMu.Lock()
+	state, err := loadState()
+	stateMu.Unlock()
+	if err != nil {
+		return InstallRecord{}, false, err
+	}
+	record, ok := state.Tools[name]
+	return record, ok, nil
+}
+
+// installedAs возвращает инструмент в том виде, в котором его установил DevOrchestrator:
+// тем же менеджером версий и той же версии или тем же пакетом. Так uninstall OpenJDK после
+// install java@21 удаляет openjdk-21-jdk, а не пакет по умолчанию. Версия, указанная
+// при удалении явно, важнее записи.
+func installedAs(tool Tool) Tool {
+	if tool.Pin != "" {
+		return tool
+	}
+	record, ok, err := installRecord(tool.Name)
+	if err != nil || !ok {
+		return tool
+	}
+	if _, ok := versionManagerByName(record.Backend); ok {
+		tool.Via = record.Backend
+		tool.Pin = record.Pin
+		tool.InstalledRelease = record.Release
+		return tool
+	}
+	if record.Package != "" {
+		tool.InstalledPackage = record.Package
+	}
+	return tool
+}
+
+// checkProvenance запрещает удалять установленные инструменты, которые ставили не мы:
+// они могли прийти с системой, и от них может зависеть ОС. С --force выводится только предупреждение.
+func checkProvenance(ctx context.Context, specs []string, osType string) error {
+	stateMu.Lock()
+	state, err := loadState()
+	stateMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var foreign []string
+	for _, spec := range specs {
+		tool, ok := lookupTool(spec)
+		if !ok {
+			continue
+		}
+		if _, ours := state.Tools[tool.Name]; !ours && tool.present(ctx, osType) {
+			foreign = append(foreign, tool.Name)
+		}
+	}
+	if len(foreign) == 0 {
+		return nil
+	}
+	if forceUninstall {
+		fmt.Printf("Внимание: %s установлены не через DevOrchestrator и будут удалены (--force).\n", strings.Join(foreign, ", "))
+		return nil
+	}
+	return fmt.Errorf("удаление отменено: %s установлены не через DevOrchestrator и могут быть нужны системе; чтобы все равно удалить, добавьте --force", strings.Join(foreign, ", "))
+}
+
+// installRecorder собирает сведения об установке одного инструмента
+type installRecorder struct {
+	mu       sync.Mutex
+	backend  string
+	pkg      string
+	pin      string
+	release  string
+	commands []string
+	// order — порядковые номера команд среди всех команд запуска, чтобы --atomic отменял их в обратном порядке
+	order []uint64
+	paths []string
+	// existing — репозитории и ключи, которые были в системе до установки инструмента;
+	// checked — уже проверенные: ключ, созданный одной командой, следующая упоминает в signed-by
+	existing map[string]bool
+	checked  map[string]bool
+}
+
+type recorderKey struct{}
+
+// commandOrder нумерует записанные команды всех инструментов в порядке выполнения
+var commandOrder atomic.Uint64
+
+// withInstallRecorder возвращает контекст, в котором runCommand записывает выполненные команды
+func withInstallRecorder(ctx context.Context) (context.Context, *installRecorder) {
+	rec := &installRecorder{}
+	return context.WithValue(ctx, recorderKey{}, rec), rec
+}
+
+// withoutRecorder возвращает контекст, команды в котором не относятся к установке инструмента
+func withoutRecorder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, recorderKey{}, (*installRecorder)(nil))
+}
+
+// recorderFrom возвращает записывающий объект из контекста или nil
+func recorderFrom(ctx context.Context) *installRecorder {
+	rec, _ := ctx.Value(recorderKey{}).(*installRecorder)
+	return rec
+}
+
+// noteBackend запоминает, чем устанавливается инструмент
+func noteBackend(ctx context.Context, backend string) {
+	if rec := recorderFrom(ctx); rec != nil {
+		rec.mu.Lock()
+		rec.backend = backend
+		rec.mu.Unlock()
+	}
+}
+
+// notePackage запоминает устанавливаемый пакет и выбранную версию
+func notePackage(ctx context.Context, pkg, pin string) {
+	if rec := recorderFrom(ctx); rec != nil {
+		rec.mu.Lock()
+		rec.pkg, rec.pin = pkg, pin
+		rec.mu.Unlock()
+	}
+}
+
+// noteRelease запоминает идентификатор версии, установленной менеджером версий
+func noteRelease(ctx context.Context, release string) {
+	if rec := recorderFrom(ctx); rec != nil {
+		rec.mu.Lock()
+		rec.release = release
+		rec.mu.Unlock()
+	}
+}
+
+// noteCommand запоминает выполненную команду
+func noteCommand(ctx context.Context, command string) {
+	if rec := recorderFrom(ctx); rec != nil {
+		rec.mu.Lock()
+		rec.commands = append(rec.commands, command)
+		rec.order = append(rec.order, commandOrder.Add(1))
+		rec.mu.Unlock()
+	}
+}
+
+// noteExistingSources запоминает репозитории и ключи из команды, которые уже есть в системе.
+// Вызывается до выполнения команды: подключенный не нами репозиторий нельзя удалять при --purge.
+// Источник проверяется только перед первой упомянувшей его командой инструмента, то есть до того,
+// как установка могла его создать.
+func noteExistingSources(ctx context.Context, command string) {
+	rec := recorderFrom(ctx)
+	if rec == nil {
+		return
+	}
+	sources := append(matchAll([]string{command}, repositoryPatterns), matchAll([]string{command}, keyPatterns)...)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.checked == nil {
+		rec.checked, rec.existing = make(map[string]bool), make(map[string]bool)
+	}
+	for _, source := range sources {
+		if rec.checked[source] {
+			continue
+		}
+		rec.checked[source] = true
+		if sourceExists(source) {
+			rec.existing[source] = true
+		}
+	}
+}
+
+// notePath запоминает файл или каталог, созданный при установке
+func notePath(ctx context.Context, path string) {
+	if rec := recorderFrom(ctx); rec != nil {
+		rec.mu.Lock()
+		rec.paths = append(rec.paths, path)
+		rec.mu.Unlock()
+	}
+}
+
+// record составляет запись об установке инструмента
+func (r *installRecorder) record(name, version string) InstallRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := InstallRecord{
+		Tool:        name,
+		Version:     version,
+		Backend:     r.backend,
+		Package:     r.pkg,
+		Pin:         r.pin,
+		Release:     r.release,
+		Commands:    append([]string{}, r.commands...),
+		Paths:       append([]string(nil), r.paths...),
+		InstalledAt: time.Now().UTC().Truncate(time.Second),
+	}
+	repositories, keys := addedSources(record.Commands)
+	record.Repositories, record.Keys = r.withoutExisting(repositories), r.withoutExisting(keys)
+	return record
+}
+
+// withoutExisting убирает из списка репозитории и ключи, существовавшие до установки
+func (r *installRecorder) withoutExisting(sources []string) []string {
+	var added []string
+	for _, source := range sources {
+		if !r.existing[source] {
+			added = append(added, source)
+		}
+	}
+	return added
+}
+
+var (
+	// repositoryPatterns находят подключенные репозитории: файлы списков и строки add-apt-repository
+	repositoryPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`/etc/apt/sources\.list\.d/[^\s'"]+`),
+		regexp.MustCompile(`/etc/yum\.repos\.d/[^\s'"]+`),
+		regexp.MustCompile(`/etc/zypp/repos\.d/[^\s'"]+`),
+		regexp.MustCompile(`add-apt-repository\s+"([^"]+)"`),
+	}
+	// keyPatterns находят добавленные ключи: файлы keyring и ключи, загруженные в apt-key
+	keyPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`/etc/apt/keyrings/[^\s'"\]]+`),
+		regexp.MustCompile(`/usr/share/keyrings/[^\s'"\]]+`),
+		regexp.MustCompile(`(https?://\S+)\s*\|\s*(?:sudo\s+)?apt-key add`),
+		regexp.MustCompile(`rpm --import\s+(\S+)`),
+	}
+)
+
+// sourcesRoot — корень файловой системы, в которой ищутся репозитории и ключи; в тестах подменяется
+var sourcesRoot = "/"
+
+// sourceExists проверяет, есть ли репозиторий или ключ в системе: файл — по наличию,
+// строку add-apt-repository — по спискам источников apt. Ключи, загружаемые по URL,
+// проверить нельзя, они считаются новыми.
+func sourceExists(source string) bool {
+	if strings.HasPrefix(source, "/") {
+		_, err := os.Stat(filepath.Join(sourcesRoot, source))
+		return err == nil
+	}
+	if strings.Contains(source, "://") && !strings.HasPrefix(source, "deb") {
+		return false
+	}
+	lists, _ := filepath.Glob(filepath.Join(sourcesRoot, "etc", "apt", "sources.list.d", "*"))
+	for _, list := range append([]string{filepath.Join(sourcesRoot, "etc", "apt", "sources.list")}, lists...) {
+		if data, err := os.ReadFile(list); err == nil && strings.Contains(string(data), source) {
+			return true
+		}
+	}
+	return false
+}
+
+// addedSources извлекает из команд подключенные репозитории и ключи
+func addedSources(commands []string) (repositories, keys []string) {
+	return matchAll(commands, repositoryPatterns), matchAll(commands, keyPatterns)
+}
+
+// matchAll возвращает уникальные совпадения шаблонов; если в шаблоне есть группа, берется она
+func matchAll(commands []string, patterns []*regexp.Regexp) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, command := range commands {
+		for _, re := range patterns {
+			for _, m := range re.FindAllStringSubmatch(command, -1) {
+				value := m[len(m)-1]
+				if !seen[value] {
+					seen[value] = true
+					result = append(result, value)
+				}
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInstallStackRecordsState(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Distro: "ubuntu", Codename: "jammy", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get", "git")
+	useTools(t, map[string]Tool{
+		"jq":  jqTool,
+		"Git": {DetectBinary: "git", Description: "Git", PackageID: "git"},
+		"Visual Studio Code": {DetectBinary: "code", Description: "Visual Studio Code", Steps: map[string][]string{"apt": {
+			"sudo install -D -o root -g root -m 644 packages.microsoft.gpg /etc/apt/keyrings/packages.microsoft.gpg",
+			`sudo sh -c 'echo "deb [arch={{.DebArch}} signed-by=/etc/apt/keyrings/packages.microsoft.gpg] https://packages.microsoft.com/repos/code stable main" > /etc/apt/sources.list.d/vscode.list'`,
+			"sudo apt install -y code",
+		}}},
+	})
+
+	if err := installStack(context.Background(), EssentialStack, []string{"Visual Studio Code"}, []string{"jq", "Git"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.Tools["Git"]; ok {
+		t.Error("установленный до нас Git записан в состояние")
+	}
+
+	jq := state.Tools["jq"]
+	if jq.Backend != "apt" || !reflect.DeepEqual(jq.Commands, []string{"sudo apt-get install -y jq"}) || jq.InstalledAt.IsZero() {
+		t.Errorf("запись jq: %+v", jq)
+	}
+	code := state.Tools["Visual Studio Code"]
+	if code.Backend != "steps" || len(code.Commands) != 3 {
+		t.Errorf("запись VS Code: %+v", code)
+	}
+	if !reflect.DeepEqual(code.Repositories, []string{"/etc/apt/sources.list.d/vscode.list"}) {
+		t.Errorf("репозитории %q", code.Repositories)
+	}
+	if !reflect.DeepEqual(code.Keys, []string{"/etc/apt/keyrings/packages.microsoft.gpg"}) {
+		t.Errorf("ключи %q", code.Keys)
+	}
+	if len(fake.commands()) != 4 {
+		t.Errorf("команды %q", fake.commands())
+	}
+}
+
+func TestUninstallRefusesToolsWeDidNotInstall(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get", "git", "jq")
+	useTools(t, map[string]Tool{
+		"jq":  jqTool,
+		"Git": {DetectBinary: "git", Description: "Git", PackageID: "git"},
+	})
+	if err := updateState(func(s *State) { s.Tools["jq"] = InstallRecord{Tool: "jq", Backend: "apt"} }); err != nil {
+		t.Fatal(err)
+	}
+
+	err := performUninstall(context.Background(), []string{"jq", "Git"}, "linux")
+	if err == nil || !strings.Contains(err.Error(), "Git установлены не через DevOrchestrator") || strings.Contains(err.Error(), "jq") {
+		t.Fatalf("ошибка %v", err)
+	}
+	if len(fake.runs) != 0 {
+		t.Fatalf("выполнены команды %q", fake.commands())
+	}
+
+	if err := performUninstall(context.Background(), []string{"jq"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"sudo apt-get remove -y jq"}) {
+		t.Errorf("команды %q", got)
+	}
+	if state, _ := loadState(); len(state.Tools) != 0 {
+		t.Errorf("запись об удаленном инструменте осталась: %+v", state.Tools)
+	}
+
+	forceUninstall = true
+	t.Cleanup(func() { forceUninstall = false })
+	if err := performUninstall(context.Background(), []string{"Git"}, "linux"); err != nil {
+		t.Fatalf("с --force: %v", err)
+	}
+}
+
+func TestUninstallRemovesRecordedPackage(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	fake.outputs["sh -c apt-cache show openjdk-21-jdk"] = "Package: openjdk-21-jdk\n"
+	useTools(t, map[string]Tool{"OpenJDK": javaTool})
+
+	if err := installStack(context.Background(), EssentialStack, nil, []string{"OpenJDK@21"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	state, _ := loadState()
+	if record := state.Tools["OpenJDK"]; record.Package != "openjdk-21-jdk" || record.Pin != "21" {
+		t.Errorf("запись OpenJDK: %+v", record)
+	}
+
+	// В системе стоит и openjdk-11-jdk из пакета по умолчанию, но удаляется установленный нами пакет
+	fake.mu.Lock()
+	fake.binaries["java"] = true
+	fake.runs = nil
+	fake.mu.Unlock()
+	if err := performUninstall(context.Background(), []string{"OpenJDK"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"sudo apt-get remove -y openjdk-21-jdk"}) {
+		t.Errorf("команды %q", got)
+	}
+}
+
+func TestInstallSkipsExistingSources(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	useFakeExecutor(t, "apt-get")
+	useTools(t, map[string]Tool{"Visual Studio Code": {DetectBinary: "code", Description: "Visual Studio Code", Steps: map[string][]string{"apt": {
+		"sudo install -D -o root -g root -m 644 packages.microsoft.gpg /etc/apt/keyrings/packages.microsoft.gpg",
+		`sudo sh -c 'echo "deb https://packages.microsoft.com/repos/code stable main" > /etc/apt/sources.list.d/vscode.list'`,
+		`sudo add-apt-repository -y "deb https://packages.microsoft.com/repos/edge stable main"`,
+		"sudo apt install -y code",
+	}}}})
+	// Репозиторий VS Code и строка Edge уже подключены пользователем, ключа еще нет
+	lists := filepath.Join(sourcesRoot, "etc", "apt", "sources.list.d")
+	if err := os.MkdirAll(lists, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(lists, "vscode.list"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(lists, "edge.list"), []byte("deb https://packages.microsoft.com/repos/edge stable main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := installStack(context.Background(), EssentialStack, []string{"Visual Studio Code"}, nil, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	state, _ := loadState()
+	record := state.Tools["Visual Studio Code"]
+	if len(record.Repositories) != 0 || !reflect.DeepEqual(record.Keys, []string{"/etc/apt/keyrings/packages.microsoft.gpg"}) {
+		t.Errorf("записаны существовавшие источники: репозитории %q, ключи %q", record.Repositories, record.Keys)
+	}
+}
+
+func TestInstallRecordsKeyReferencedAfterCreation(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	const installKey = "sudo install -D -o root -g root -m 644 packages.microsoft.gpg /etc/apt/keyrings/packages.microsoft.gpg"
+	useTools(t, map[string]Tool{"Visual Studio Code": {DetectBinary: "code", Description: "Visual Studio Code", Steps: map[string][]string{"apt": {
+		installKey,
+		`sudo sh -c 'echo "deb [signed-by=/etc/apt/keyrings/packages.microsoft.gpg] https://packages.microsoft.com/repos/code stable main" > /etc/apt/sources.list.d/vscode.list'`,
+		"sudo apt install -y code",
+	}}}})
+	// Ключ появляется на диске после первого шага, и второй шаг ссылается на уже существующий файл
+	fake.onRun = func(command string) {
+		if command == installKey {
+			key := filepath.Join(sourcesRoot, "etc", "apt", "keyrings", "packages.microsoft.gpg")
+			if err := os.MkdirAll(filepath.Dir(key), 0o755); err == nil {
+				_ = os.WriteFile(key, nil, 0o644)
+			}
+		}
+	}
+
+	if err := installStack(context.Background(), EssentialStack, []string{"Visual Studio Code"}, nil, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	state, _ := loadState()
+	record := state.Tools["Visual Studio Code"]
+	if !reflect.DeepEqual(record.Keys, []string{"/etc/apt/keyrings/packages.microsoft.gpg"}) {
+		t.Errorf("созданный при установке ключ не записан: %q", record.Keys)
+	}
+	if !reflect.DeepEqual(record.Repositories, []string{"/etc/apt/sources.list.d/vscode.list"}) {
+		t.Errorf("репозитории %q", record.Repositories)
+	}
+}
+
+func TestPurgeToolRemovedByHand(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	useTools(t, map[string]Tool{"jq": jqTool})
+	record := InstallRecord{Tool: "jq", Backend: "apt", Repositories: []string{"deb [arch=amd64] https://example.com/it's stable main"}}
+	if err := updateState(func(s *State) { s.Tools["jq"] = record }); err != nil {
+		t.Fatal(err)
+	}
+	purgeUninstall = true
+	t.Cleanup(func() { purgeUninstall = false })
+
+	// jq удален вручную, но подключенный при установке репозиторий остался
+	err := performUninstall(context.Background(), []string{"jq"}, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`sudo add-apt-repository --remove 'deb [arch=amd64] https://example.com/it'\''s stable main'`}
+	if got := fake.commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("команды %q, ожидалось %q", got, want)
+	}
+	if state, _ := loadState(); len(state.Tools) != 0 {
+		t.Errorf("запись об удаленном инструменте осталась: %+v", state.Tools)
+	}
+}
+
+func TestUninstallPurgeRemovesRecordedArtifacts(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, ".zshrc"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ohMyZsh := func(ctx context.Context) error { return os.Mkdir(filepath.Join(home, ".oh-my-zsh"), 0o755) }
+	useTools(t, map[string]Tool{
+		"Zsh": {DetectBinary: "zsh", Description: "Zsh", PackageID: "zsh", InstallFunc: ohMyZsh, HookPaths: []string{"~/.oh-my-zsh", "~/.zshrc"}},
+		"Visual Studio Code": {
+			DetectBinary: "code",
+			Description:  "Visual Studio Code",
+			Steps: map[string][]string{"apt": {
+				`sudo sh -c 'echo "deb [signed-by=/etc/apt/keyrings/packages.microsoft.gpg] https://packages.microsoft.com/repos/code stable main" > /etc/apt/sources.list.d/vscode.list'`,
+				"curl -fsSL https://example.com/old.gpg | sudo apt-key add -",
+				"sudo apt install -y code",
+			}},
+			UndoSteps: map[string][]string{"apt": {"sudo apt remove -y code"}},
+		},
+	})
+
+	if err := installStack(context.Background(), EssentialStack, []string{"Visual Studio Code"}, []string{"Zsh"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	state, _ := loadState()
+	if got := state.Tools["Zsh"].Paths; !reflect.DeepEqual(got, []string{filepath.Join(home, ".oh-my-zsh")}) {
+		t.Errorf("запомнены пути %q; существовавший .zshrc запоминать нельзя", got)
+	}
+
+	fake.mu.Lock()
+	fake.binaries["zsh"], fake.binaries["code"] = true, true
+	fake.runs = nil
+	fake.mu.Unlock()
+	purgeUninstall = true
+	t.Cleanup(func() { purgeUninstall = false })
+
+	if err := performUninstall(context.Background(), []string{"Zsh", "Visual Studio Code"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	// zsh был в системе, DevOrchestrator поставил только Oh My Zsh: пакет zsh не удаляется
+	got := fake.commands()
+	for _, want := range []string{
+		"rm -rf '" + filepath.Join(home, ".oh-my-zsh") + "'",
+		"sudo apt remove -y code",
+		"sudo rm -f /etc/apt/sources.list.d/vscode.list",
+		"sudo rm -f /etc/apt/keyrings/packages.microsoft.gpg",
+	} {
+		if !contains(got, want) {
+			t.Errorf("нет команды %q в %q", want, got)
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("лишние команды: %q", got)
+	}
+}
+
+func TestPurgeZapsHomebrewCasks(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "darwin", Arch: "arm64"})
+	fake := useFakeExecutor(t, "brew", "postman")
+	purgeUninstall = true
+	t.Cleanup(func() { purgeUninstall = false })
+	postman := Tool{DetectBinary: "postman", Description: "Postman", Packages: map[string]string{"brew": "--cask postman"}}
+
+	if err := executeCommand(context.Background(), "darwin", "uninstall", postman); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"brew uninstall --zap --cask postman"}) {
+		t.Errorf("команды %q", got)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var statusStack string
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Показать, какие инструменты установлены, их версии и источник",
+	Example: "  dev-installer status --stack Golang\n" +
+		"  dev-installer status --output json",
+	RunE: statusRun,
+}
+
+func init() {
+	statusCmd.Flags().StringVar(&statusStack, "stack", "", "проверить только инструменты указанного стека")
+}
+
+// ToolStatus — состояние инструмента в системе
+type ToolStatus struct {
+	Name      string `json:"name"`
+	Command   string `json:"command"`
+	Installed bool   `json:"installed"`
+	// Path — путь к исполняемому файлу, найденному в PATH
+	Path string `json:"path,omitempty"`
+	// Version — установленная версия, приведенная к semver
+	Version string `json:"version,omitempty"`
+	// PackageVersion — версия пакета в терминах пакетного менеджера
+	PackageVersion string `json:"package_version,omitempty"`
+	// Manager — пакетный или версионный менеджер, которому принадлежит найденный в PATH файл
+	Manager string `json:"manager,omitempty"`
+	// DetectedBy — стратегия, по которой обнаружен инструмент: path, package, snap, app, winget, registry
+	DetectedBy string `json:"detected_by,omitempty"`
+	// Provenance — запись об установке через DevOrchestrator; пусто, если инструмент ставили не мы
+	Provenance *InstallRecord `json:"provenance,omitempty"`
+}
+
+func statusRun(cmd *cobra.Command, args []string) error {
+	osType := detectOS()
+
+	names, err := statusToolNames(statusStack)
+	if err != nil {
+		return err
+	}
+
+	// Отсутствие пакетного менеджера не мешает проверить инструменты по PATH
+	pm, _ := detectPackageManager(osType)
+	statuses := inspectTools(cmd.Context(), names, osType, pm)
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	for i := range statuses {
+		if record, ok := state.Tools[statuses[i].Name]; ok {
+			statuses[i].Provenance = &record
+		}
+	}
+
+	if outputFormat == "json" {
+		enc := json.NewEncoder(resultOutput)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	}
+
+	w := tabwriter.NewWriter(resultOutput, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ИНСТРУМЕНТ\tСОСТОЯНИЕ\tВЕРСИЯ\tМЕНЕДЖЕР\tОБНАРУЖЕН\tИСТОЧНИК\tПУТЬ")
+	for _, s := range statuses {
+		state := "не установлен"
+		if s.Installed {
+			state = "установлен"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, state, dash(s.Version), dash(s.Manager), dash(s.DetectedBy), provenance(s), dash(s.Path))
+	}
+	return w.Flush()
+}
+
+// inspectTools параллельно проверяет инструменты, сохраняя порядок
+func inspectTools(ctx context.Context, names []string, osType string, pm PackageManager) []ToolStatus {
+	statuses := make([]ToolStatus, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			statuses[i] = inspectTool(ctx, name, availableTools[name], osType, pm)
+		}(i, name)
+	}
+	wg.Wait()
+	return statuses
+}
+
+// inspectTool определяет состояние инструмента стратегиями обнаружения и запросом к пакетному менеджеру
+func inspectTool(ctx context.Context, name string, tool Tool, osType string, pm PackageManager) ToolStatus {
+	status := ToolStatus{
+		Name:    name,
+		Command: tool.DetectBinary,
+	}
+	if detection, found := tool.detect(ctx, osType); found {
+		status.Installed = true
+		status.DetectedBy = detection.Strategy
+	}
+	if path, err := executor.LookPath(tool.DetectBinary); err == nil {
+		status.Path = path
+		status.Manager = binaryManager(ctx, path, pm)
+	}
+
+	if pm != nil {
+		if pkg, err := tool.resolvedPackage(osType, pm.Name()); err == nil && pm.IsInstalled(ctx, pkg) {
+			status.Installed = true
+			// Пакет может быть установлен, а в PATH первым найден другой файл, например шим pyenv:
+			// тогда менеджер и версия пакета к запускаемому файлу не относятся
+			if status.Path == "" {
+				status.Manager = pm.Name()
+			}
+			if status.Manager == pm.Name() {
+				if version, err := pm.InstalledVersion(ctx, pkg); err == nil {
+					status.PackageVersion = version
+				}
+			}
+		}
+	}
+
+	// Версия из самого инструмента точнее версии пакета, но есть не у всех инструментов
+	if status.Installed {
+		if version, err := tool.installedVersion(ctx, osType); err == nil {
+			status.Version = version.String()
+		} else if version, err := parseVersion(status.PackageVersion); err == nil {
+			status.Version = version.String()
+		}
+	}
+	return status
+}
+
+// binaryManager определяет, чем установлен исполняемый файл path: менеджер версий узнается
+// по каталогу файла или цели ссылки, snap — по /snap/, иначе файл проверяется у пакетного менеджера.
+// Пустая строка означает, что владелец неизвестен, например файл скопирован вручную.
+func binaryManager(ctx context.Context, path string, pm PackageManager) string {
+	candidates := []string{path}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != path {
+		candidates = append(candidates, resolved)
+	}
+	for _, candidate := range candidates {
+		slashed := filepath.ToSlash(candidate)
+		for _, m := range versionManagers {
+			for _, fragment := range m.paths {
+				if strings.Contains(slashed, fragment) {
+					return m.name
+				}
+			}
+		}
+		if strings.HasPrefix(slashed, "/snap/") {
+			return "snap"
+		}
+	}
+	if pm != nil {
+		for _, candidate := range candidates {
+			if pm.Owns(ctx, candidate) {
+				return pm.Name()
+			}
+		}
+	}
+	return ""
+}
+
+// provenance описывает, кто установил инструмент
+func provenance(s ToolStatus) string {
+	switch {
+	case s.Provenance != nil:
+		return fmt.Sprintf("DevOrchestrator (%s, %s)", dash(s.Provenance.Backend), s.Provenance.InstalledAt.Local().Format("2006-01-02"))
+	case s.Installed:
+		return "не DevOrchestrator"
+	default:
+		return "-"
+	}
+}
+
+// dash заменяет пустое значение прочерком
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// statusToolNames возвращает отсортированный список инструментов для проверки
+func statusToolNames(stackName string) ([]string, error) {
+	var names []string
+	if stackName == "" {
+		for name := range availableTools {
+			names = append(names, name)
+		}
+	} else {
+		stack, err := parseStack(stackName)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, ideOptions(stack)...)
+		names = append(names, toolsByStack[string(stack)]...)
+	}
+	sort.Strings(names)
+	return dedupe(names), nil
+}
+
+// dedupe удаляет повторы из отсортированного списка
+func dedupe(sorted []string) []string {
+	var result []string
+	for i, name := range sorted {
+		if i > 0 && sorted[i-1] == name {
+			continue
+		}
+		result = append(result, name)
+	}
+	return result
+}
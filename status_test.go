@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestInspectTool(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get", "jq")
+	fake.outputs["sh -c dpkg-query -W -f='${Status}' jq"] = "install ok installed"
+	fake.outputs["sh -c dpkg-query -W -f='${Version}' jq"] = "1.6-2.1"
+	fake.outputs["sh -c dpkg -S '/usr/bin/jq'"] = "jq: /usr/bin/jq\n"
+	pm := packageManagerByName("apt")
+
+	// Без команды версии используется версия пакета
+	got := inspectTool(context.Background(), "jq", jqTool, "linux", pm)
+	want := ToolStatus{Name: "jq", Command: "jq", Installed: true, Path: "/usr/bin/jq", Version: "1.6.0", PackageVersion: "1.6-2.1", Manager: "apt", DetectedBy: "path"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inspectTool = %+v, ожидалось %+v", got, want)
+	}
+
+	// Версия, которую сообщает сам инструмент, важнее версии пакета
+	probed := jqTool
+	probed.VersionCommand = "jq --version"
+	fake.outputs["sh -c jq --version"] = "jq-1.7.1\n"
+	if got := inspectTool(context.Background(), "jq", probed, "linux", pm); got.Version != "1.7.1" {
+		t.Errorf("версия %q, ожидалась 1.7.1", got.Version)
+	}
+
+	got = inspectTool(context.Background(), "Git", Tool{DetectBinary: "git"}, "linux", pm)
+	if got.Installed || got.Path != "" || got.Manager != "" {
+		t.Errorf("отсутствующий инструмент: %+v", got)
+	}
+}
+
+func TestInspectToolReportsManagerOfResolvedBinary(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	fake.paths = map[string]string{"python3": "/home/dev/.pyenv/shims/python3"}
+	fake.outputs["sh -c dpkg-query -W -f='${Status}' python3"] = "install ok installed"
+	fake.outputs["sh -c dpkg-query -W -f='${Version}' python3"] = "3.10.6-1"
+	fake.outputs["sh -c python3 --version"] = "Python 3.12.4\n"
+	python := Tool{DetectBinary: "python3", PackageID: "python3", VersionCommand: "python3 --version"}
+
+	got := inspectTool(context.Background(), "Python 3", python, "linux", packageManagerByName("apt"))
+	if got.Manager != "pyenv" || got.PackageVersion != "" || got.Version != "3.12.4" {
+		t.Errorf("шим pyenv приписан системному пакету: %+v", got)
+	}
+
+	// Файл, который не принадлежит ни одному пакету, показывается без менеджера
+	fake.paths["python3"] = "/usr/local/bin/python3"
+	if got := inspectTool(context.Background(), "Python 3", python, "linux", packageManagerByName("apt")); got.Manager != "" {
+		t.Errorf("менеджер %q для файла вне пакетов", got.Manager)
+	}
+}
+
+func TestAptIgnoresRemovedPackageConfig(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	pm := packageManagerByName("apt")
+
+	// После apt remove без purge dpkg помнит пакет в состоянии config-files
+	fake.outputs["sh -c dpkg-query -W -f='${Status}' jq"] = "deinstall ok config-files"
+	if pm.IsInstalled(context.Background(), "jq") {
+		t.Error("пакет с оставшимися настройками считается установленным")
+	}
+	fake.outputs["sh -c dpkg-query -W -f='${Status}' jq"] = "install ok installed"
+	if !pm.IsInstalled(context.Background(), "jq") {
+		t.Error("установленный пакет не найден")
+	}
+}
+
+func TestChocoMissingPackageNotInstalled(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "windows", Arch: "amd64"})
+	fake := useFakeExecutor(t, "choco")
+	pm := packageManagerByName("choco")
+	const query = "powershell -Command choco list --local-only --exact --limit-output jq"
+
+	// Без --limit-output choco печатает "0 packages installed." и завершается успешно
+	fake.outputs[query] = "Chocolatey v2.2.2\n0 packages installed.\n"
+	if pm.IsInstalled(context.Background(), "jq") {
+		t.Error("отсутствующий пакет choco считается установленным")
+	}
+	fake.outputs[query] = "jq|1.7.1\r\n"
+	if !pm.IsInstalled(context.Background(), "jq") {
+		t.Error("установленный пакет choco не найден")
+	}
+}
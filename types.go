@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 )
 
 // Stack представляет тип стека технологий
@@ -16,45 +22,267 @@ const (
 	EssentialStack  Stack = "Essential Tools"
 )
 
+// allStacks перечисляет стеки в порядке отображения
+var allStacks = []Stack{FrontendStack, JavaKotlinStack, GolangStack, PythonStack, EssentialStack}
+
+// Action представляет действие над инструментами
+type Action string
+
+const (
+	ActionInstall   Action = "Установить"
+	ActionUpdate    Action = "Обновить"
+	ActionUninstall Action = "Удалить"
+)
+
 // Tool представляет инструмент разработчика
 type Tool struct {
-	Command     string
+	// Name — ключ инструмента в каталоге; заполняется в lookupTool
+	Name string
+	// DetectBinary — исполняемый файл, по которому определяется, установлен ли инструмент
+	DetectBinary string
+	// PackageID — имя пакета по умолчанию, если для менеджера или ОС не указано свое.
+	// Не совпадает с DetectBinary: Maven ставится пакетом maven, а запускается как mvn.
+	PackageID   string
 	Description string
-	InstallFunc func() error
+	InstallFunc func(ctx context.Context) error
+	// Packages сопоставляет пакетный менеджер или ОС с именем пакета
+	Packages map[string]string
+	// Steps содержит специальные команды установки для пакетного менеджера или ОС
+	Steps map[string][]string
+	// UndoSteps — команды удаления для инструментов, установленных командами Steps
+	UndoSteps map[string][]string
+	// HookPaths — файлы и каталоги, которые создает InstallFunc
+	HookPaths []string
+	// Unsupported — пакетные менеджеры, в репозиториях которых нет пакета инструмента
+	Unsupported []string
+	// Detect — дополнительные способы обнаружить инструмент, кроме PATH и пакетного менеджера
+	Detect ToolDetect
+	// VersionCommand печатает версию инструмента, VersionPattern выделяет ее из вывода
+	VersionCommand string
+	VersionPattern *regexp.Regexp
+	// Versions и VersionedPackages позволяют установить конкретную версию
+	Versions          []string
+	VersionedPackages map[string]string
+	// Pin — запрошенное ограничение версии ("20", ">=1.22,<1.24"); пусто, если версия не важна
+	Pin string
+	// Backends описывает инструмент для менеджеров версий (sdkman, fnm, pyenv...)
+	Backends map[string]ToolBackend
+	// Requires — инструменты, которые должны быть установлены раньше этого
+	Requires []string
+	// Via — менеджер версий, через который работаем с инструментом; пусто — системный пакетный менеджер
+	Via string
+	// InstalledPackage — пакет из записи об установке; если задан, удаляется именно он
+	InstalledPackage string
+	// InstalledRelease — идентификатор версии в менеджере версий из записи об установке
+	InstalledRelease string
 }
 
 // install устанавливает инструмент
-func (t Tool) install(osType string) error {
-	if !isInstalled(t.Command, osType) {
-		if t.InstallFunc != nil {
-			return t.InstallFunc()
+func (t Tool) install(ctx context.Context, osType string) error {
+	if dryRun {
+		dryRunPlan.begin(t.Description)
+	}
+	if t.Via != "" {
+		// Менеджер версий сам пропускает уже установленную версию, поэтому конкретную версию ставим всегда
+		if t.Pin != "" || !t.present(ctx, osType) {
+			return executeCommand(ctx, osType, "install", t)
 		}
-		return executeCommand(osType, "install", t.Command)
+		fmt.Printf("%s уже установлен через %s.\n", t.Description, t.Via)
+		return skipped("уже установлен через %s", t.Via)
 	}
-	fmt.Printf("%s уже установлен.\n", t.Description)
-	return nil
+	detection, found := t.detect(ctx, osType)
+	if !found {
+		if t.InstallFunc != nil && t.Pin == "" {
+			noteBackend(ctx, "hook")
+			return t.runHook(ctx)
+		}
+		return executeCommand(ctx, osType, "install", t)
+	}
+	if t.Pin != "" {
+		// Установленная версия может не подходить: тогда ставим нужную рядом с ней
+		if satisfied, installed := t.pinSatisfied(ctx, osType); !satisfied {
+			fmt.Printf("%s: установлена версия %s, требуется %s.\n", t.Description, installed, t.Pin)
+			return executeCommand(ctx, osType, "install", t)
+		}
+	}
+	fmt.Printf("%s уже установлен (%s).\n", t.Description, detection)
+	return skipped("уже установлен (%s)", detection.Strategy)
 }
 
 // update обновляет инструмент
-func (t Tool) update(osType string) error {
-	if isInstalled(t.Command, osType) {
+func (t Tool) update(ctx context.Context, osType string) error {
+	if dryRun {
+		dryRunPlan.begin(t.Description)
+	}
+	if t.present(ctx, osType) {
 		log.Printf("Обновление %s...\n", t.Description)
-		return executeCommand(osType, "update", t.Command)
+		// Версию из менеджера версий видно только в его окружении, поэтому не сравниваем
+		if dryRun || t.VersionCommand == "" || t.Via != "" {
+			return executeCommand(ctx, osType, "update", t)
+		}
+
+		before, beforeErr := t.installedVersion(ctx, osType)
+		if err := executeCommand(ctx, osType, "update", t); err != nil {
+			return err
+		}
+		after, afterErr := t.installedVersion(ctx, osType)
+		switch {
+		case beforeErr != nil || afterErr != nil:
+		case after.Compare(before) == 0:
+			fmt.Printf("%s: установлена последняя доступная версия %s.\n", t.Description, after)
+		default:
+			fmt.Printf("%s: %s → %s\n", t.Description, before, after)
+		}
+		return nil
 	}
 	fmt.Printf("%s не установлен.\n", t.Description)
-	return nil
+	return errNotInstalled
 }
 
 // uninstall удаляет инструмент
-func (t Tool) uninstall(osType string) error {
-	if isInstalled(t.Command, osType) {
+func (t Tool) uninstall(ctx context.Context, osType string) error {
+	if dryRun {
+		dryRunPlan.begin(t.Description)
+	}
+	if t.present(ctx, osType) {
 		log.Printf("Удаление %s...\n", t.Description)
-		return executeCommand(osType, "uninstall", t.Command)
+		return executeCommand(ctx, osType, "uninstall", t)
 	}
 	fmt.Printf("%s не установлен.\n", t.Description)
+	return errNotInstalled
+}
+
+// uninstallHook удаляет файлы и каталоги, созданные встроенной функцией установки.
+// Пакет инструмента в этом случае был в системе до DevOrchestrator, поэтому не удаляется.
+func (t Tool) uninstallHook(ctx context.Context, record InstallRecord, osType string) error {
+	if dryRun {
+		dryRunPlan.begin(t.Description)
+	}
+	commands, _ := purgeCommands(InstallRecord{Paths: record.Paths})
+	if len(commands) == 0 {
+		fmt.Printf("%s: встроенная функция не создала файлов, удалять нечего.\n", t.Description)
+		return errNotInstalled
+	}
+	log.Printf("Удаление файлов встроенной функции %s...\n", t.Description)
+	for _, command := range commands {
+		if err := runCommand(ctx, command, osType); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// present проверяет, установлен ли инструмент: через выбранный менеджер версий
+// или стратегиями обнаружения detect
+func (t Tool) present(ctx context.Context, osType string) bool {
+	if m, ok := versionManagerByName(t.Via); ok {
+		return m.isInstalled(ctx, t, osType)
+	}
+	_, found := t.detect(ctx, osType)
+	return found
+}
+
+// packageName возвращает имя пакета для пакетного менеджера pm.
+// Имя для менеджера важнее имени для ОС, а оно — имени по умолчанию PackageID.
+// Пустая строка означает, что пакета для менеджера нет.
+func (t Tool) packageName(osType, pm string) string {
+	if contains(t.Unsupported, pm) {
+		return ""
+	}
+	if name := t.Packages[pm]; name != "" {
+		return name
+	}
+	if name := t.Packages[osType]; name != "" {
+		return name
+	}
+	return t.PackageID
+}
+
+// installedVersion возвращает установленную версию инструмента
+func (t Tool) installedVersion(ctx context.Context, osType string) (Version, error) {
+	if t.VersionCommand == "" {
+		return Version{}, fmt.Errorf("для %s не задана команда получения версии", t.Description)
+	}
+	output, err := commandOutput(ctx, t.VersionCommand, osType)
+	if err != nil {
+		return Version{}, fmt.Errorf("ошибка получения версии %s: %v", t.Description, err)
+	}
+	return extractVersion(output, t.VersionPattern)
+}
+
+// pinSatisfied проверяет, удовлетворяет ли установленная версия ограничению t.Pin
+func (t Tool) pinSatisfied(ctx context.Context, osType string) (bool, string) {
+	constraint, err := parseConstraint(t.Pin)
+	if err != nil {
+		return false, "неизвестна"
+	}
+	installed, err := t.installedVersion(ctx, osType)
+	if err != nil {
+		return false, "неизвестна"
+	}
+	return constraint.Check(installed), installed.String()
+}
+
+// resolvedPackage возвращает имя пакета для менеджера pm с подстановкой данных о платформе.
+// Если запрошена версия, используется версионированный пакет.
+func (t Tool) resolvedPackage(osType, pm string) (string, error) {
+	if t.Pin != "" {
+		return t.pinnedPackage(osType, pm)
+	}
+	name := t.packageName(osType, pm)
+	if name == "" {
+		return "", fmt.Errorf("в каталоге нет пакета для %s", pm)
+	}
+	return currentPlatform().expand(name)
+}
+
+// installSteps возвращает специальные команды установки для менеджера pm или ОС
+func (t Tool) installSteps(osType, pm string) []string {
+	if steps, ok := t.Steps[pm]; ok {
+		return steps
+	}
+	return t.Steps[osType]
+}
+
+// undoSteps возвращает команды удаления для менеджера pm или ОС
+func (t Tool) undoSteps(osType, pm string) []string {
+	if steps, ok := t.UndoSteps[pm]; ok {
+		return steps
+	}
+	return t.UndoSteps[osType]
+}
+
+// runHook выполняет встроенную функцию установки и запоминает созданные ею файлы и каталоги.
+// Пути, которые существовали до установки, не запоминаются: их создал не DevOrchestrator.
+// Созданное прерванной функцией тоже запоминается, чтобы --atomic мог его удалить.
+func (t Tool) runHook(ctx context.Context) error {
+	var created []string
+	for _, path := range t.HookPaths {
+		if _, err := os.Stat(expandHome(path)); errors.Is(err, os.ErrNotExist) {
+			created = append(created, expandHome(path))
+		}
+	}
+	err := t.InstallFunc(ctx)
+	for _, path := range created {
+		if _, statErr := os.Stat(path); statErr == nil {
+			notePath(ctx, path)
+		}
+	}
+	return err
+}
+
+// expandHome заменяет ~ в начале пути домашним каталогом
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
 // StringToStack конвертирует строку в тип Stack
 func StringToStack(s string) Stack {
 	switch s {
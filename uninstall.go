@@ -2,45 +2,41 @@ package main
 
 import (
 	"fmt"
-	"log"
-	"sync"
 
 	"github.com/spf13/cobra"
 )
 
+var uninstallFlags selectionFlags
+
 var uninstallCmd = &cobra.Command{
-	Use:   "uninstall",
+	Use:   "uninstall [инструменты...]",
 	Short: "Удалить инструменты",
-	Run:   uninstallRun,
+	RunE:  uninstallRun,
+}
+
+func init() {
+	uninstallCmd.Flags().StringVar(&uninstallFlags.stack, "stack", "", "стек разработки, из которого выбираются инструменты")
+	uninstallCmd.Flags().BoolVar(&purgeUninstall, "purge", false, "удалить также репозитории, ключи, настройки и файлы, добавленные при установке")
+	uninstallCmd.Flags().BoolVar(&forceUninstall, "force", false, "удалять и инструменты, установленные не через DevOrchestrator")
 }
 
-func uninstallRun(cmd *cobra.Command, args []string) {
+func uninstallRun(cmd *cobra.Command, args []string) error {
 	osType := detectOS()
 	fmt.Printf("Обнаруженная ОС: %s\n", osType)
 
-	stackStr := selectStack()
-	tools := selectStackTools(stackStr)
-
-	var wg sync.WaitGroup
-	errorsCh := make(chan error, len(tools))
-
-	for _, toolName := range tools {
-		if tool, ok := availableTools[toolName]; ok {
-			wg.Add(1)
-			go func(tool Tool) {
-				defer wg.Done()
-				err := tool.uninstall(osType)
-				if err != nil {
-					errorsCh <- fmt.Errorf("ошибка удаления %s: %v", tool.Description, err)
-				}
-			}(tool)
-		}
+	stack, err := resolveStack(uninstallFlags, args)
+	if err != nil {
+		return err
+	}
+	tools, err := resolveTools(cmd.Context(), stack, args)
+	if err != nil {
+		return err
 	}
 
-	wg.Wait()
-	close(errorsCh)
-
-	for err := range errorsCh {
-		log.Printf("Ошибка: %v\n", err)
+	if interactive(args) {
+		if err := confirmSelection(cmd.Context(), ActionUninstall, stack, nil, tools, osType); err != nil {
+			return err
+		}
 	}
+	return performAction(cmd.Context(), ActionUninstall, stack, nil, tools, osType)
 }
@@ -2,45 +2,39 @@ package main
 
 import (
 	"fmt"
-	"log"
-	"sync"
 
 	"github.com/spf13/cobra"
 )
 
+var updateFlags selectionFlags
+
 var updateCmd = &cobra.Command{
-	Use:   "update",
+	Use:   "update [инструменты...]",
 	Short: "Обновить инструменты",
-	Run:   updateRun,
+	RunE:  updateRun,
+}
+
+func init() {
+	updateCmd.Flags().StringVar(&updateFlags.stack, "stack", "", "стек разработки, из которого выбираются инструменты")
 }
 
-func updateRun(cmd *cobra.Command, args []string) {
+func updateRun(cmd *cobra.Command, args []string) error {
 	osType := detectOS()
 	fmt.Printf("Обнаруженная ОС: %s\n", osType)
 
-	stackStr := selectStack()
-	tools := selectStackTools(stackStr)
-
-	var wg sync.WaitGroup
-	errorsCh := make(chan error, len(tools))
-
-	for _, toolName := range tools {
-		if tool, ok := availableTools[toolName]; ok {
-			wg.Add(1)
-			go func(tool Tool) {
-				defer wg.Done()
-				err := tool.update(osType)
-				if err != nil {
-					errorsCh <- fmt.Errorf("ошибка обновления %s: %v", tool.Description, err)
-				}
-			}(tool)
-		}
+	stack, err := resolveStack(updateFlags, args)
+	if err != nil {
+		return err
+	}
+	tools, err := resolveTools(cmd.Context(), stack, args)
+	if err != nil {
+		return err
 	}
 
-	wg.Wait()
-	close(errorsCh)
-
-	for err := range errorsCh {
-		log.Printf("Ошибка: %v\n", err)
+	if interactive(args) {
+		if err := confirmSelection(cmd.Context(), ActionUpdate, stack, nil, tools, osType); err != nil {
+			return err
+		}
 	}
+	return performAction(cmd.Context(), ActionUpdate, stack, nil, tools, osType)
 }
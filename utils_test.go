@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var jqTool = Tool{
+	DetectBinary: "jq",
+	Description:  "jq",
+	Packages:     map[string]string{"choco": "jq", "brew": "jq", "linux": "jq", "emerge": "app-misc/jq"},
+}
+
+func TestExecuteCommandPerPackageManager(t *testing.T) {
+	tests := []struct {
+		pm        string
+		osType    string
+		binary    string
+		install   string
+		update    string
+		uninstall string
+	}{
+		{"apt", "linux", "apt-get", "sudo apt-get install -y jq", "sudo apt-get install --only-upgrade -y jq", "sudo apt-get remove -y jq"},
+		{"dnf", "linux", "dnf", "sudo dnf install -y jq", "sudo dnf upgrade -y jq", "sudo dnf remove -y jq"},
+		{"yum", "linux", "yum", "sudo yum install -y jq", "sudo yum update -y jq", "sudo yum remove -y jq"},
+		{"pacman", "linux", "pacman", "sudo pacman -S --noconfirm --needed jq", "sudo pacman -Syu --noconfirm jq", "sudo pacman -R --noconfirm jq"},
+		{"zypper", "linux", "zypper", "sudo zypper --non-interactive install jq", "sudo zypper --non-interactive update jq", "sudo zypper --non-interactive remove jq"},
+		{"apk", "linux", "apk", "sudo apk add jq", "sudo apk add --upgrade jq", "sudo apk del jq"},
+		{"xbps", "linux", "xbps-install", "sudo xbps-install -y jq", "sudo xbps-install -yu jq", "sudo xbps-remove -y jq"},
+		{"emerge", "linux", "emerge", "sudo emerge --ask=n --noreplace app-misc/jq", "sudo emerge --ask=n --update app-misc/jq", "sudo emerge --ask=n --depclean app-misc/jq"},
+		{"brew", "darwin", "brew", "brew install jq", "brew upgrade jq", "brew uninstall jq"},
+		{"choco", "windows", "choco", "choco install -y jq", "choco upgrade -y jq", "choco uninstall -y jq"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pm, func(t *testing.T) {
+			useTestPlatform(t, Platform{OS: tt.osType, Arch: "amd64"})
+			for command, want := range map[string]string{"install": tt.install, "update": tt.update, "uninstall": tt.uninstall} {
+				fake := useFakeExecutor(t, tt.binary)
+				if err := executeCommand(context.Background(), tt.osType, command, jqTool); err != nil {
+					t.Fatalf("%s: %v", command, err)
+				}
+				if got := fake.commands(); !reflect.DeepEqual(got, []string{want}) {
+					t.Errorf("%s: команды %q, ожидалось %q", command, got, want)
+				}
+				if fake.runs[0].OS != tt.osType {
+					t.Errorf("%s: команда выполнена для ОС %s", command, fake.runs[0].OS)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteCommandWithoutSudoForRoot(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	isRoot = func() bool { return true }
+
+	if err := executeCommand(context.Background(), "linux", "install", jqTool); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"apt-get install -y jq"}) {
+		t.Errorf("команды %q", got)
+	}
+}
+
+func TestExecuteCommandPrefersDistroManager(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get", "dnf")
+	if err := os.WriteFile(osReleasePath, []byte("ID=fedora\nVERSION_ID=40\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := executeCommand(context.Background(), "linux", "install", jqTool); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"sudo dnf install -y jq"}) {
+		t.Errorf("команды %q", got)
+	}
+}
+
+func TestExecuteCommandSpecialSteps(t *testing.T) {
+	docker := Tool{
+		DetectBinary: "docker",
+		Packages:     map[string]string{"apt": "docker.io", "linux": "docker"},
+		Steps: map[string][]string{
+			"apt": {"sudo add-apt-repository \"deb [arch={{.DebArch}}] https://download.docker.com/linux/{{.Distro}} {{.Codename}} stable\"", "sudo apt install -y docker-ce"},
+		},
+	}
+	useTestPlatform(t, Platform{OS: "linux", Distro: "ubuntu", Codename: "jammy", Arch: "arm64"})
+
+	fake := useFakeExecutor(t, "apt-get")
+	if err := executeCommand(context.Background(), "linux", "install", docker); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"sudo add-apt-repository \"deb [arch=arm64] https://download.docker.com/linux/ubuntu jammy stable\"",
+		"sudo apt install -y docker-ce",
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("apt: команды %q, ожидалось %q", got, want)
+	}
+
+	// Для другого менеджера шаги apt не применяются
+	fake = useFakeExecutor(t, "dnf")
+	if err := executeCommand(context.Background(), "linux", "install", docker); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"sudo dnf install -y docker"}) {
+		t.Errorf("dnf: команды %q", got)
+	}
+
+	// Специальные шаги используются только при установке
+	fake = useFakeExecutor(t, "apt-get")
+	if err := executeCommand(context.Background(), "linux", "uninstall", docker); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"sudo apt-get remove -y docker.io"}) {
+		t.Errorf("uninstall: команды %q", got)
+	}
+}
+
+func TestExecuteCommandWithoutPackageManager(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	useFakeExecutor(t)
+
+	err := executeCommand(context.Background(), "linux", "install", jqTool)
+	if err == nil || !strings.Contains(err.Error(), "не найден поддерживаемый пакетный менеджер") {
+		t.Fatalf("ошибка %v", err)
+	}
+}
+
+func TestInstallStackRequiresAdminOnWindows(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "windows", Arch: "amd64"})
+	fake := useFakeExecutor(t, "choco")
+	useTools(t, map[string]Tool{"jq": jqTool})
+	fake.outputs[`powershell -Command [bool](([System.Security.Principal.WindowsIdentity]::GetCurrent()).groups -match "S-1-5-32-544")`] = "False\r\n"
+
+	err := installStack(context.Background(), EssentialStack, nil, []string{"jq"}, "windows")
+	if err == nil || !strings.Contains(err.Error(), "правами администратора") {
+		t.Fatalf("ошибка %v", err)
+	}
+	if len(fake.runs) != 0 {
+		t.Errorf("без прав администратора выполнены команды %q", fake.commands())
+	}
+}
+
+func TestInstallStackStopsOnFailure(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	fake.failOn = []string{"docker.io"}
+	useTools(t, map[string]Tool{
+		"Docker":         {DetectBinary: "docker", Description: "Docker", Packages: map[string]string{"apt": "docker.io"}},
+		"Docker Compose": {DetectBinary: "docker-compose", Description: "Docker Compose", Requires: []string{"Docker"}},
+	})
+
+	err := installStack(context.Background(), EssentialStack, nil, []string{"Docker Compose", "Docker"}, "linux")
+	if err == nil || !strings.Contains(err.Error(), "ошибка установки Docker") {
+		t.Fatalf("ошибка %v", err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"sudo apt-get install -y docker.io"}) {
+		t.Errorf("после ошибки выполнены команды %q", got)
+	}
+}
+
+func TestInstallStackKeepGoingReportsEveryTool(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get", "jq")
+	fake.failOn = []string{"docker.io"}
+	useTools(t, map[string]Tool{
+		"jq":             jqTool,
+		"Git":            {DetectBinary: "git", Description: "Git", Packages: map[string]string{"linux": "git"}},
+		"Docker":         {DetectBinary: "docker", Description: "Docker", Packages: map[string]string{"apt": "docker.io"}},
+		"Docker Compose": {DetectBinary: "docker-compose", Description: "Docker Compose", Requires: []string{"Docker"}},
+	})
+	keepGoing = true
+	t.Cleanup(func() { keepGoing = false })
+	var out bytes.Buffer
+	resultOutput = &out
+	t.Cleanup(func() { resultOutput = os.Stdout })
+
+	err := installStack(context.Background(), EssentialStack, nil, []string{"Docker", "Docker Compose", "jq", "Git"}, "linux")
+	if err == nil || !strings.Contains(err.Error(), "ошибка установки Docker") {
+		t.Fatalf("ошибка %v", err)
+	}
+	if got := fake.commands(); !contains(got, "sudo apt-get install -y git") {
+		t.Errorf("после ошибки Docker не установлен Git: %q", got)
+	}
+	table := strings.Join(strings.Fields(out.String()), " ")
+	for _, want := range []string{
+		"Docker Compose ошибка Docker Compose пропущен: не выполнена зависимость Docker",
+		"jq пропущено уже установлен",
+		"Git выполнено -",
+		"Итого: выполнено 1, пропущено 1, ошибок 2",
+	} {
+		if !strings.Contains(table, want) {
+			t.Errorf("в итоге нет %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestInstallStackSkipsInstalledAndUnknownTools(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get", "jq")
+	useTools(t, map[string]Tool{
+		"jq":  jqTool,
+		"Git": {DetectBinary: "git", Description: "Git", Packages: map[string]string{"linux": "git"}},
+	})
+
+	if err := installStack(context.Background(), EssentialStack, []string{"Нет такой IDE"}, []string{"jq", "Git"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, []string{"sudo apt-get install -y git"}) {
+		t.Errorf("команды %q", got)
+	}
+}
+
+func TestInstallStackPropagatesHookError(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	fake.failOn = []string{"ohmyzsh"}
+	useTools(t, map[string]Tool{"Zsh": {DetectBinary: "zsh", Description: "Zsh", InstallFunc: installOhMyZsh}})
+
+	err := installStack(context.Background(), EssentialStack, nil, []string{"Zsh"}, "linux")
+	if err == nil || !strings.Contains(err.Error(), "Oh My Zsh") {
+		t.Fatalf("ошибка %v", err)
+	}
+}
+
+func TestParallelInstallSerializesPackageManager(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get")
+	useTools(t, map[string]Tool{
+		"jq":   jqTool,
+		"Git":  {DetectBinary: "git", Description: "Git", Packages: map[string]string{"linux": "git"}},
+		"Curl": {DetectBinary: "curl", Description: "Curl", Packages: map[string]string{"linux": "curl"}},
+	})
+
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	fake.onRun = func(command string) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}
+
+	if err := installStack(context.Background(), EssentialStack, nil, []string{"jq", "Git", "Curl"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.runs) != 3 {
+		t.Fatalf("команды %q", fake.commands())
+	}
+	if maxActive != 1 {
+		t.Errorf("одновременно выполнялось %d команд apt-get", maxActive)
+	}
+}
+
+func TestPackageIndexRefreshedOnceBeforeFirstInstall(t *testing.T) {
+	useTestPlatform(t, Platform{OS: "linux", Arch: "amd64"})
+	fake := useFakeExecutor(t, "apt-get", "git")
+	useStaleIndexes(t)
+	useTools(t, map[string]Tool{
+		"jq":   jqTool,
+		"Curl": {DetectBinary: "curl", Description: "Curl", PackageID: "curl"},
+		"Git":  {DetectBinary: "git", Description: "Git", PackageID: "git"},
+	})
+
+	forceUninstall = true
+	t.Cleanup(func() { forceUninstall = false })
+	if err := performUninstall(context.Background(), []string{"Git"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+	if err := installStack(context.Background(), EssentialStack, nil, []string{"jq", "Curl"}, "linux"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := fake.commands()
+	if len(got) != 4 || got[0] != "sudo apt-get remove -y git" || got[1] != "sudo apt-get update" {
+		t.Fatalf("команды %q: индекс должен обновляться один раз перед первой установкой", got)
+	}
+	state, _ := loadState()
+	if commands := state.Tools["jq"].Commands; !reflect.DeepEqual(commands, []string{"sudo apt-get install -y jq"}) {
+		t.Errorf("обновление индекса попало в запись об установке: %q", commands)
+	}
+}
+
+func TestRefreshSkipsPartialUpgradeAndPortageSync(t *testing.T) {
+	fake := useFakeExecutor(t, "pacman", "emerge")
+	for _, name := range []string{"pacman", "emerge"} {
+		if err := packageManagerByName(name).Refresh(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := fake.commands(); len(got) != 0 {
+		t.Errorf("обновление индекса выполнило %q", got)
+	}
+}
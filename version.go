@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version — версия инструмента, приведенная к виду major.minor.patch
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// defaultVersionPattern находит первую версию вида 1.2 или 1.2.3 в выводе команды
+var defaultVersionPattern = regexp.MustCompile(`(\d+(?:\.\d+)+)`)
+
+// versionNumbers выделяет числовую часть версии
+var versionNumbers = regexp.MustCompile(`^\d+(?:\.\d+)*`)
+
+// legacyJavaVersion — схема версий Java до 9: "1.8.0_392" означает 8.0.392
+var legacyJavaVersion = regexp.MustCompile(`^1\.(\d+)\.(\d+)_(\d+)`)
+
+// parseVersion приводит строку версии к semver.
+// Понимает префикс "v" и "go", эпоху пакетов Debian ("1:2.39.5"), ревизию пакета ("-0+deb12u2")
+// и старую схему Java ("1.8.0_392" — это 8.0.392, как у Java 9+). Отсутствующие компоненты считаются нулями.
+func parseVersion(s string) (Version, error) {
+	raw := s
+	s = strings.TrimSpace(s)
+	if _, rest, ok := strings.Cut(s, ":"); ok {
+		s = rest
+	}
+	s = strings.TrimPrefix(s, "go")
+	s = strings.TrimPrefix(s, "v")
+
+	if m := legacyJavaVersion.FindStringSubmatch(s); m != nil {
+		// Иначе Java 8 получила бы major 1, и ограничение java@8 никогда бы не выполнялось
+		s = m[1] + "." + m[2] + "." + m[3]
+	}
+
+	numbers := versionNumbers.FindString(s)
+	if numbers == "" {
+		return Version{}, fmt.Errorf("не удалось разобрать версию %q", raw)
+	}
+
+	var parts [3]int
+	for i, field := range strings.SplitN(numbers, ".", 4) {
+		if i == len(parts) {
+			break
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return Version{}, fmt.Errorf("не удалось разобрать версию %q: %v", raw, err)
+		}
+		parts[i] = n
+	}
+	return Version{Major: parts[0], Minor: parts[1], Patch: parts[2]}, nil
+}
+
+// extractVersion находит версию в выводе команды по шаблону с одной группой
+func extractVersion(output string, pattern *regexp.Regexp) (Version, error) {
+	if pattern == nil {
+		pattern = defaultVersionPattern
+	}
+	match := pattern.FindStringSubmatch(output)
+	if len(match) < 2 {
+		return Version{}, fmt.Errorf("версия не найдена в выводе %q", strings.TrimSpace(firstLine(output)))
+	}
+	return parseVersion(match[1])
+}
+
+// firstLine возвращает первую строку текста
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// String возвращает версию в виде major.minor.patch
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare возвращает -1, 0 или 1, если v меньше, равна или больше other
+func (v Version) Compare(other Version) int {
+	for _, d := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		switch {
+		case d[0] < d[1]:
+			return -1
+		case d[0] > d[1]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionComparator — одно условие ограничения версии
+type versionComparator struct {
+	op      string
+	version Version
+	// parts — сколько компонентов указано явно (для "20" или "~1.22")
+	parts int
+}
+
+// Constraint — набор условий на версию, которые должны выполняться одновременно,
+// например ">=1.22,<1.24"
+type Constraint struct {
+	raw         string
+	comparators []versionComparator
+}
+
+// constraintPattern разбирает одно условие: оператор и версию
+var constraintPattern = regexp.MustCompile(`^(>=|<=|==|!=|>|<|=|~|\^)?\s*v?(\d+(?:\.\d+){0,2})$`)
+
+// operatorSpacing убирает пробелы между оператором и версией (">= 1.22")
+var operatorSpacing = regexp.MustCompile(`([<>=!~^]+)\s+`)
+
+// parseConstraint разбирает ограничение версии.
+// Поддерживаются операторы =, ==, !=, >, >=, <, <=, ~ (совпадение до минорной версии)
+// и ^ (совпадение мажорной версии). Версия без оператора ("20", "1.22") означает
+// совпадение по указанным компонентам. Условия разделяются запятыми или пробелами.
+func parseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	s = operatorSpacing.ReplaceAllString(s, "$1")
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		match := constraintPattern.FindStringSubmatch(field)
+		if match == nil {
+			return Constraint{}, fmt.Errorf("неверное ограничение версии %q", c.raw)
+		}
+		version, err := parseVersion(match[2])
+		if err != nil {
+			return Constraint{}, err
+		}
+		c.comparators = append(c.comparators, versionComparator{
+			op:      match[1],
+			version: version,
+			parts:   strings.Count(match[2], ".") + 1,
+		})
+	}
+	if len(c.comparators) == 0 {
+		return Constraint{}, fmt.Errorf("пустое ограничение версии")
+	}
+	return c, nil
+}
+
+// Check сообщает, удовлетворяет ли версия ограничению
+func (c Constraint) Check(v Version) bool {
+	for _, cmp := range c.comparators {
+		if !cmp.check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// String возвращает исходную запись ограничения
+func (c Constraint) String() string {
+	return c.raw
+}
+
+func (cmp versionComparator) check(v Version) bool {
+	r := v.Compare(cmp.version)
+	switch cmp.op {
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	case "!=":
+		return r != 0
+	case "=", "==":
+		return r == 0
+	case "~":
+		return r >= 0 && v.Major == cmp.version.Major && v.Minor == cmp.version.Minor
+	case "^":
+		return r >= 0 && v.Major == cmp.version.Major
+	default:
+		return matchesPrefix(v, cmp.version, cmp.parts)
+	}
+}
+
+// matchesPrefix сравнивает только явно указанные компоненты версии
+func matchesPrefix(v, want Version, parts int) bool {
+	if v.Major != want.Major {
+		return false
+	}
+	if parts >= 2 && v.Minor != want.Minor {
+		return false
+	}
+	if parts >= 3 && v.Patch != want.Patch {
+		return false
+	}
+	return true
+}
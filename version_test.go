@@ -0,0 +1,107 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.22":               "1.22.0",
+		"v20.11.0":           "20.11.0",
+		"go1.22.1":           "1.22.1",
+		"1:2.39.5-0+deb12u2": "2.39.5",
+		"1.8.0_392":          "8.0.392",
+		"1.7.0_80-b15":       "7.0.80",
+		"21":                 "21.0.0",
+		"3.11.2-1+b1":        "3.11.2",
+		"1.2.3.4":            "1.2.3",
+	}
+	for input, want := range tests {
+		got, err := parseVersion(input)
+		if err != nil {
+			t.Errorf("parseVersion(%q): %v", input, err)
+			continue
+		}
+		if got.String() != want {
+			t.Errorf("parseVersion(%q) = %s, ожидалось %s", input, got, want)
+		}
+	}
+
+	if _, err := parseVersion("latest"); err == nil {
+		t.Error("parseVersion(\"latest\") должен вернуть ошибку")
+	}
+}
+
+func TestExtractVersion(t *testing.T) {
+	tests := []struct {
+		output  string
+		pattern string
+		want    string
+	}{
+		{"go version go1.22.1 linux/amd64", `go(\d+(?:\.\d+)*)`, "1.22.1"},
+		{"v20.11.0\n", "", "20.11.0"},
+		{"openjdk version \"21\" 2023-09-19\nOpenJDK Runtime Environment", `version "([^"]+)"`, "21.0.0"},
+		{"openjdk version \"1.8.0_392\"", `version "([^"]+)"`, "8.0.392"},
+		{"Docker version 24.0.7, build afdd53b", "", "24.0.7"},
+		{"jq-1.6", "", "1.6.0"},
+		{"\n------------------------------------------------------------\nGradle 8.5\n", `Gradle (\S+)`, "8.5.0"},
+	}
+	for _, tt := range tests {
+		var pattern *regexp.Regexp
+		if tt.pattern != "" {
+			pattern = regexp.MustCompile(tt.pattern)
+		}
+		got, err := extractVersion(tt.output, pattern)
+		if err != nil {
+			t.Errorf("extractVersion(%q): %v", tt.output, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("extractVersion(%q) = %s, ожидалось %s", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=1.22,<1.24", "1.22.0", true},
+		{">=1.22,<1.24", "1.23.9", true},
+		{">=1.22,<1.24", "1.24.0", false},
+		{">= 1.22, < 1.24", "1.21.5", false},
+		{"20", "20.11.0", true},
+		{"20", "21.0.0", false},
+		{"1.22", "1.22.7", true},
+		{"1.22", "1.23.0", false},
+		{"~1.22.3", "1.22.9", true},
+		{"~1.22.3", "1.22.1", false},
+		{"^20.1", "20.9.0", true},
+		{"^20.1", "21.0.0", false},
+		{"=21.0.2", "21.0.2", true},
+		{"!=21.0.2", "21.0.2", false},
+		{">8", "11.0.0", true},
+		{"8", "1.8.0_392", true},
+		{">=11", "1.8.0_392", false},
+	}
+	for _, tt := range tests {
+		c, err := parseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("parseConstraint(%q): %v", tt.constraint, err)
+			continue
+		}
+		v, _ := parseVersion(tt.version)
+		if got := c.Check(v); got != tt.want {
+			t.Errorf("%q.Check(%s) = %v, ожидалось %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "latest", ">=", "1.x", "=>1.2"} {
+		if _, err := parseConstraint(bad); err == nil {
+			t.Errorf("parseConstraint(%q) должен вернуть ошибку", bad)
+		}
+	}
+}